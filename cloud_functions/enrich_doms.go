@@ -21,6 +21,14 @@ func init() {
 	functions.HTTP("enrich", handleDomainEnrichment(bqs))
 }
 
+// matchedDomainsResponse adds the errors of failed strategies and, when requested, the hosts each matched domain
+// was observed on
+type matchedDomainsResponse struct {
+	domains.MatchedDomainsByStrategy
+	Hosts            map[string][]string       `json:"hosts,omitempty"`
+	EnrichmentErrors []domains.EnrichmentError `json:"enrichmentErrors,omitempty"`
+}
+
 func handleDomainEnrichment(bqs *bq.BQStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var rParams types.RequestParams
@@ -33,23 +41,35 @@ func handleDomainEnrichment(bqs *bq.BQStore) http.HandlerFunc {
 			return
 		}
 		log.Println(rParams)
-		doms, err := bqs.GetDomainsByNames(context.Background(), rParams.DomainNames)
+		// Enrichment stops once the client disconnects or the request times out, whatever was gathered is still saved
+		ctx := r.Context()
+		doms, err := bqs.GetDomainsByNames(ctx, rParams.DomainNames)
 		if err != nil {
 			writeJSON(
 				w, http.StatusInternalServerError, map[string]string{"error": "Unable to get domains from BQ"},
 			)
 			return
 		}
-		enrichDomains(doms, rParams.ProcessConfig)
+		enrichDomains(ctx, doms, rParams.ProcessConfig)
+		linkDomains(ctx, bqs, doms, rParams.EnrichmentConfig)
 		go log.Println(bqs.PutDomains(context.Background(), doms))
 		if rParams.NoResponse {
 			writeJSON(w, http.StatusOK, map[string]string{"message": "Enriched domains"})
 			return
 		}
+		if !rParams.IncludeHosts {
+			for _, dom := range doms {
+				dom.StripHosts()
+			}
+		}
 		if rParams.OnlyMatchedDomains {
-			matchedDoms := make(map[string]domains.MatchedDomainsByStrategy)
+			matchedDoms := make(map[string]matchedDomainsResponse)
 			for _, dom := range doms {
-				matchedDoms[dom.DomainName] = dom.GetAllMatchedDomains()
+				matchedDoms[dom.DomainName] = matchedDomainsResponse{
+					MatchedDomainsByStrategy: dom.GetAllMatchedDomains(),
+					Hosts:                    dom.GetAllMatchedHosts(),
+					EnrichmentErrors:         dom.EnrichmentErrors,
+				}
 			}
 			writeJSON(w, http.StatusOK, matchedDoms)
 		} else {
@@ -1,18 +1,22 @@
 package cloud_functions
 
 import (
+	"context"
+	"log"
 	"sync"
 
 	"dev.azure.com/Unum/Mkt_Analytics/_git/cloud_functions/types"
 	"github.com/herzs11/domwalk/domains"
+	"github.com/herzs11/domwalk/stores/bq"
 )
 
-func enrichDomains(doms []*domains.Domain, cfg types.ProcessConfig) {
+// enrichDomains enriches doms across the configured workers, abandoning in-flight work once ctx is done
+func enrichDomains(ctx context.Context, doms []*domains.Domain, cfg types.ProcessConfig) {
 	jobs := make(chan *domains.Domain, len(doms))
 	var wg sync.WaitGroup
 	wg.Add(cfg.Workers)
 	for w := 1; w <= cfg.Workers; w++ {
-		go enrichDomainWorker(w, jobs, &wg, cfg.EnrichmentConfig)
+		go enrichDomainWorker(ctx, w, jobs, &wg, cfg.EnrichmentConfig)
 	}
 	for _, dom := range doms {
 		jobs <- dom
@@ -21,9 +25,66 @@ func enrichDomains(doms []*domains.Domain, cfg types.ProcessConfig) {
 	wg.Wait()
 }
 
-func enrichDomainWorker(id int, jobs <-chan *domains.Domain, wg *sync.WaitGroup, cfg domains.EnrichmentConfig) {
+func enrichDomainWorker(
+	ctx context.Context, id int, jobs <-chan *domains.Domain, wg *sync.WaitGroup, cfg domains.EnrichmentConfig,
+) {
 	defer wg.Done()
 	for domain := range jobs {
-		domain.Enrich(cfg)
+		domain.EnrichContext(ctx, cfg)
+	}
+}
+
+// linkDomains forms the relationships that depend on other previously enriched domains
+func linkDomains(ctx context.Context, bqs *bq.BQStore, doms []*domains.Domain, cfg domains.EnrichmentConfig) {
+	if cfg.Whois {
+		idx, err := bqs.GetRegistrantIndex(ctx, doms)
+		if err != nil {
+			log.Printf("Error building registrant index: %s\n", err)
+		} else {
+			for _, dom := range doms {
+				dom.GetWhoisRegistrantDomains(idx)
+			}
+		}
+	}
+	if cfg.CertSans {
+		idx, err := bqs.GetCertOrgIndex(ctx, doms, cfg.CertOrgNormalization)
+		if err != nil {
+			log.Printf("Error building certificate organization index: %s\n", err)
+		} else {
+			for _, dom := range doms {
+				dom.GetCertOrgDomains(idx, cfg.CertOrgNormalization)
+			}
+		}
+	}
+	if cfg.DNS {
+		idx, err := bqs.GetSharedInfraIndex(ctx, doms)
+		if err != nil {
+			log.Printf("Error building shared mail and nameserver index: %s\n", err)
+		} else {
+			for _, dom := range doms {
+				dom.GetSharedMXDomains(idx)
+				dom.GetSharedNSDomains(idx)
+			}
+		}
+		l, err := cfg.SharedHostDenylist()
+		if err != nil {
+			log.Printf("Error parsing shared host denylist: %s\n", err)
+		} else if idx, err := bqs.GetSharedIPIndex(ctx, doms, l); err != nil {
+			log.Printf("Error building shared IP index: %s\n", err)
+		} else {
+			for _, dom := range doms {
+				dom.GetSharedIPDomains(idx, l)
+			}
+		}
+	}
+	if cfg.Trackers {
+		idx, err := bqs.GetSharedTrackerIndex(ctx, doms)
+		if err != nil {
+			log.Printf("Error building shared tracker index: %s\n", err)
+		} else {
+			for _, dom := range doms {
+				dom.GetSharedTrackerDomains(idx)
+			}
+		}
 	}
 }
@@ -36,11 +36,24 @@ var rootCmd = &cobra.Command{
 
 	Currently, the tool can enrich domains with the following relationships:
 	- Certificate Subject Alternative Names (SANs)
+	- Certificate Subject Organizations (verified OV/EV certificates)
+	- Certificate Transparency Log SANs (including certificates no longer served)
 	- Web Redirects
 	- SitemapLoc Web Domains
 	- SitemapLoc Contact Page Domains
+	- Homepage Links (footer, navigation, body and asset links of the landing page)
+	- Shared Analytics and Tag Accounts (Google Analytics, Tag Manager, Meta Pixel, HubSpot, Adobe Launch, ...)
+	- Structured Data Organizations (schema.org sameAs, parent and sub organizations)
+	- WHOIS/RDAP Registrants
+	- SPF Includes
+	- DMARC Report Destinations
+	- Shared Dedicated IP Addresses (from DNS data)
+	- Shared Self-Hosted Mail Exchangers and Vanity Nameservers (from DNS data)
 
-	The tool can also enrich domains with DNS data. In a future version, this dns data will be used to form additional domain relationships
+	The tool can also enrich domains with DNS data, TLS certificate metadata and WHOIS/RDAP registration data. A and AAAA records are
+	reverse resolved, and addresses belonging to CDNs and shared hosts are excluded from shared IP matching. Mail
+	exchangers and nameservers run by well-known providers, such as Google Workspace, Microsoft 365, Cloudflare
+	and Route53, are likewise excluded from shared mail and nameserver matching
 	`,
 	Example: `domwalk domains -d unum.com,coloniallife.com --workers 20 --cert-sans --web-redirects --sitemaps --dns`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
@@ -55,46 +68,104 @@ var rootCmd = &cobra.Command{
 			color.Red(err.Error())
 		}
 		domainsToExecute = args
-		cs, _ := cmd.Flags().GetBool("cert-sans")
-		wr, _ := cmd.Flags().GetBool("web-redirects")
-		sm, _ := cmd.Flags().GetBool("sitemaps")
-		dns, _ := cmd.Flags().GetBool("dns")
+		ctSource, _ := cmd.Flags().GetString("ct-source")
+		dkimSelectors, _ := cmd.Flags().GetStringSlice("dkim-selectors")
+		certHosts, _ := cmd.Flags().GetStringSlice("cert-hosts")
+		certPorts, _ := cmd.Flags().GetIntSlice("cert-ports")
+		orgCaseSensitive, _ := cmd.Flags().GetBool("cert-org-case-sensitive")
+		orgKeepPunctuation, _ := cmd.Flags().GetBool("cert-org-keep-punctuation")
+		orgSuffixes, _ := cmd.Flags().GetStringSlice("cert-org-suffixes")
+		sharedHostPTRs, _ := cmd.Flags().GetStringSlice("shared-host-ptrs")
+		sharedHostCIDRs, _ := cmd.Flags().GetStringSlice("shared-host-cidrs")
+		if _, err := domains.NewSharedHostDenylist(sharedHostPTRs, sharedHostCIDRs); err != nil {
+			color.Red("Invalid shared-host-cidrs: %s\n", err.Error())
+			os.Exit(1)
+		}
 		workers, _ := cmd.Flags().GetInt("workers")
 		if workers < 1 {
 			color.Red("Workers must be greater than 0\n")
 			os.Exit(1)
 		}
+		resolvers, _ := cmd.Flags().GetStringSlice("resolvers")
+		dnsTimeout, _ := cmd.Flags().GetDuration("dns-timeout")
+		dnsTransport, _ := cmd.Flags().GetString("dns-transport")
+		if _, err := domains.NewDNSTransport(dnsTransport, dnsTimeout); err != nil {
+			color.Red("Invalid dns-transport, must be one of udp, tcp, dot, doh or doh-get\n")
+			os.Exit(1)
+		}
+		httpTimeout, _ := cmd.Flags().GetDuration("http-timeout")
+		httpMaxBody, _ := cmd.Flags().GetInt64("http-max-body-bytes")
+		httpMaxConns, _ := cmd.Flags().GetInt("http-max-conns-per-host")
+		userAgent, _ := cmd.Flags().GetString("user-agent")
+		httpProxy, _ := cmd.Flags().GetString("http-proxy")
+		caBundle, _ := cmd.Flags().GetString("ca-bundle")
+		httpConfig := domains.HTTPConfig{
+			Timeout: httpTimeout, MaxBodyBytes: httpMaxBody, UserAgent: userAgent, Proxy: httpProxy,
+			CABundle: caBundle, MaxConnsPerHost: httpMaxConns,
+		}
+		landingVariants, _ := cmd.Flags().GetStringSlice("landing-variants")
+		for i, v := range landingVariants {
+			lv, err := domains.ParseLandingVariant(v)
+			if err != nil {
+				color.Red("%s\n", err.Error())
+				os.Exit(1)
+			}
+			landingVariants[i] = lv
+		}
+		domainTimeout, _ := cmd.Flags().GetDuration("domain-timeout")
+		if domainTimeout < 0 {
+			color.Red("Domain timeout must not be negative\n")
+			os.Exit(1)
+		}
 		minDate, _ := cmd.Flags().GetString("min-freshness")
 		staleDate, err := time.Parse(time.DateOnly, minDate)
 		if err != nil {
 			color.Red("Invalid date format for min-freshness: (YYYY-MM-DD)\n")
 			os.Exit(1)
 		}
-		if !cs && !wr && !sm && !dns {
-			cs = true
-			wr = true
-			sm = true
-			dns = true
-		}
-		processConfig = ProcessConfig{
-			Workers: workers,
-			EnrichmentConfig: domains.EnrichmentConfig{
-				CertSans:         cs,
-				DNS:              dns,
-				Sitemap:          sm,
-				WebRedirect:      wr,
-				MinFreshnessDate: staleDate,
+		cfg := domains.EnrichmentConfig{
+			CTSource:              ctSource,
+			MinFreshnessDate:      staleDate,
+			DomainTimeout:         domainTimeout,
+			HTTP:                  httpConfig,
+			LandingVariants:       landingVariants,
+			Resolvers:             resolvers,
+			DNSTimeout:            dnsTimeout,
+			DNSTransport:          dnsTransport,
+			DkimSelectors:         dkimSelectors,
+			SharedHostPTRSuffixes: sharedHostPTRs,
+			SharedHostCIDRs:       sharedHostCIDRs,
+			CertProbeHosts:        certHosts,
+			CertProbePorts:        certPorts,
+			CertOrgNormalization: domains.OrgNormalization{
+				CaseSensitive: orgCaseSensitive, KeepPunctuation: orgKeepPunctuation, Suffixes: orgSuffixes,
 			},
 		}
+		// Every strategy runs when none is selected
+		selected := false
+		for _, e := range domains.Enrichers() {
+			if on, _ := cmd.Flags().GetBool(domains.StrategyFlag(e)); on {
+				cfg.Enable(e.Name())
+				selected = true
+			}
+		}
+		if !selected {
+			for _, e := range domains.Enrichers() {
+				cfg.Enable(e.Name())
+			}
+		}
+		processConfig = ProcessConfig{Workers: workers, EnrichmentConfig: cfg}
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		noReturn, _ := cmd.Flags().GetBool("no-return")
 		onlyMatched, _ := cmd.Flags().GetBool("only-matched")
+		hosts, _ := cmd.Flags().GetBool("hosts")
 		rParams = RequestParams{
 			DomainNames:        domainsToExecute,
 			ProcessConfig:      processConfig,
 			NoResponse:         noReturn,
 			OnlyMatchedDomains: onlyMatched,
+			IncludeHosts:       hosts,
 		}
 		reqBody, err := json.Marshal(rParams)
 		if err != nil {
@@ -166,27 +237,98 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	addStrategyFlags()
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// addStrategyFlags adds a flag for each strategy. Strategies are registered from init funcs in any order, so the
+// flags of registered strategies are added again once they all are
+func addStrategyFlags() {
+	for _, e := range domains.Enrichers() {
+		if rootCmd.PersistentFlags().Lookup(domains.StrategyFlag(e)) == nil {
+			rootCmd.PersistentFlags().Bool(domains.StrategyFlag(e), false, e.Description())
+		}
+	}
+}
+
 func init() {
+	addStrategyFlags()
 	rootCmd.PersistentFlags().String(
 		"min-freshness", "0001-01-01", "Minimum date to refresh relationships, (YYYY-MM-DD)",
 	)
-	rootCmd.PersistentFlags().Bool("cert-sans", false, "Enrich domains with cert SANs")
-	rootCmd.PersistentFlags().Bool("web-redirects", false, "Enrich domains with web redirects")
-	rootCmd.PersistentFlags().Bool("sitemaps", false, "Enrich domains with sitemap web domains")
-	rootCmd.PersistentFlags().Bool("dns", false, "Enrich domains with dns data")
+	rootCmd.PersistentFlags().StringSlice(
+		"cert-hosts", []string{},
+		"Hosts, relative to each domain, probed for certificates (default apex and www). An empty entry is the apex",
+	)
+	rootCmd.PersistentFlags().IntSlice(
+		"cert-ports", []int{},
+		"Ports probed on each cert host (default 443). 25 and 587 use SMTP STARTTLS, 143 IMAP STARTTLS",
+	)
+	rootCmd.PersistentFlags().Bool(
+		"cert-org-case-sensitive", false, "Match certificate subject organizations case sensitively",
+	)
+	rootCmd.PersistentFlags().Bool(
+		"cert-org-keep-punctuation", false, "Keep punctuation when matching certificate subject organizations",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"cert-org-suffixes", []string{},
+		"Legal entity suffixes stripped from certificate subject organizations, instead of the defaults",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"landing-variants", []string{},
+		"Starts tried in order until one lands, for web redirects and sitemaps (default https://,http://,https://www.,http://www.)",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"resolvers", []string{}, "DNS servers to query in order, defaults to the cloud function's resolv.conf",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"shared-host-ptrs", []string{}, "PTR suffixes of shared hosts to exclude from shared IP matching, added to the defaults",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"shared-host-cidrs", []string{}, "CIDRs of shared hosts to exclude from shared IP matching, added to the defaults",
+	)
+	rootCmd.PersistentFlags().Duration("dns-timeout", 0, "Timeout for each DNS server queried")
+	rootCmd.PersistentFlags().String(
+		"dns-transport", "udp", "DNS transport to use: udp, tcp, dot (DNS-over-TLS), doh or doh-get (DNS-over-HTTPS)",
+	)
+	rootCmd.PersistentFlags().String(
+		"ct-source", "",
+		"CT source on the cloud function: a crt.sh-compatible URL with {domain}, a CT mirror directory or a bulk certificate file (default crt.sh)",
+	)
+	rootCmd.PersistentFlags().StringSlice("dkim-selectors", []string{}, "DKIM selectors to query instead of the defaults")
+	rootCmd.PersistentFlags().Duration("http-timeout", 0, "Timeout for each web request, body included (default 10s)")
+	rootCmd.PersistentFlags().Int64(
+		"http-max-body-bytes", 0, "Maximum size of web responses read, larger ones are cut off (default 10MiB)",
+	)
+	rootCmd.PersistentFlags().Int(
+		"http-max-conns-per-host", 0, "Idle web connections kept open to each host for reuse (default 4)",
+	)
+	rootCmd.PersistentFlags().String(
+		"user-agent", "", "User-Agent of web requests (default \"Mozilla/5.0 (compatible; domwalk/1.0)\")",
+	)
+	rootCmd.PersistentFlags().String(
+		"http-proxy", "", "Proxy URL for web requests on the cloud function, instead of its HTTP_PROXY and HTTPS_PROXY",
+	)
+	rootCmd.PersistentFlags().String(
+		"ca-bundle", "", "PEM file on the cloud function of certificates trusted for web requests, in addition to the system roots",
+	)
+	rootCmd.PersistentFlags().Duration(
+		"domain-timeout", 0, "Deadline for enriching each domain, strategies not yet run when it passes are skipped",
+	)
 	rootCmd.PersistentFlags().IntP("workers", "w", 15, "Number of concurrent workers to use")
 	rootCmd.PersistentFlags().BoolP("no-return", "q", false, "Do not return results")
 	rootCmd.PersistentFlags().BoolP("only-matched", "m", false, "Only return matched domains")
+	rootCmd.PersistentFlags().Bool(
+		"hosts", false, "Also return the hosts, subdomains included, each matched domain was observed on",
+	)
 	rootCmd.PersistentFlags().StringP(
 		"output", "o", "", "Output JSON file for results, cannot be used with --no-return",
 	)
 	rootCmd.MarkFlagsMutuallyExclusive("no-return", "output")
 	rootCmd.MarkFlagsMutuallyExclusive("no-return", "only-matched")
+	rootCmd.MarkFlagsMutuallyExclusive("no-return", "hosts")
 
 }
@@ -9,6 +9,8 @@ type RequestParams struct {
 	ProcessConfig
 	NoResponse         bool `json:"no_response,omitempty"`
 	OnlyMatchedDomains bool `json:"only_matched_domains,omitempty"`
+	// IncludeHosts returns the hosts each relationship was observed on alongside the matched domains
+	IncludeHosts bool `json:"include_hosts,omitempty"`
 }
 
 type ProcessConfig struct {
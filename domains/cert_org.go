@@ -0,0 +1,137 @@
+package domains
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+type CertOrgDomain struct {
+	MatchedDomain
+	// Organization is the normalized subject organization shared by both certificates
+	Organization string `json:"organization,omitempty"`
+}
+
+// OrgNormalization controls how certificate subject organizations are compared
+type OrgNormalization struct {
+	CaseSensitive   bool `json:"case_sensitive,omitempty"`
+	KeepPunctuation bool `json:"keep_punctuation,omitempty"`
+	// Suffixes replaces OrgSuffixes when set
+	Suffixes []string `json:"suffixes,omitempty"`
+}
+
+var (
+	// MaxCertOrgDomains is the number of indexed domains on one certificate organization above which the
+	// organization is treated as a hosting provider or CDN issuing certificates for its customers
+	MaxCertOrgDomains = 25
+)
+
+// OrgSuffixes are legal entity designations stripped, in any case, from the end of organization names
+var OrgSuffixes = []string{
+	"inc", "incorporated", "llc", "l.l.c.", "ltd", "limited", "corp", "corporation", "co", "company", "plc", "lp",
+	"llp", "gmbh", "ag", "kg", "sa", "s.a.", "sas", "sarl", "srl", "spa", "s.p.a.", "bv", "b.v.", "nv", "n.v.",
+	"pty", "ab", "as", "asa", "oy", "kk", "pte", "pvt", "sl", "s.l.",
+}
+
+func (n OrgNormalization) normalizeWords(s string) string {
+	if !n.CaseSensitive {
+		s = strings.ToLower(s)
+	}
+	if !n.KeepPunctuation {
+		s = strings.Map(
+			func(r rune) rune {
+				switch {
+				// Dropping dots and apostrophes keeps L.L.C. and O'Brien as one word
+				case r == '.' || r == '\'':
+					return -1
+				case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r):
+					return r
+				}
+				return ' '
+			}, s,
+		)
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Normalize returns the comparable form of an organization name, or "" when nothing remains
+func (n OrgNormalization) Normalize(org string) string {
+	suffixes := n.Suffixes
+	if len(suffixes) == 0 {
+		suffixes = OrgSuffixes
+	}
+	strip := make(map[string]bool)
+	for _, s := range suffixes {
+		strip[strings.ToLower(strings.TrimRight(n.normalizeWords(s), ".,"))] = true
+	}
+	words := strings.Fields(n.normalizeWords(org))
+	for len(words) > 1 && strip[strings.ToLower(strings.TrimRight(words[len(words)-1], ".,"))] {
+		words = words[:len(words)-1]
+	}
+	return strings.TrimRight(strings.Join(words, " "), " .,")
+}
+
+// OrgKey strips a normalized organization to lowercase letters and digits. Every organization with the same
+// normalized form, under any OrgNormalization, starts with its OrgKey
+func OrgKey(normalized string) string {
+	return strings.Map(
+		func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, normalized,
+	)
+}
+
+// CertOrganization returns the normalized subject organization of the domain's certificate. Organizations
+// on certificates that failed verification aren't vetted by a CA, so they are ignored
+func (d *Domain) CertOrganization(n OrgNormalization) string {
+	if d.Certificate == nil || !d.Certificate.ChainValid || d.Certificate.SelfSigned {
+		return ""
+	}
+	return n.Normalize(d.Certificate.SubjectOrganization)
+}
+
+// NewCertOrgIndex indexes enriched domains by their normalized certificate subject organization
+func NewCertOrgIndex(doms []*Domain, n OrgNormalization) DomainIndex {
+	idx := make(DomainIndex)
+	for _, d := range doms {
+		if org := d.CertOrganization(n); org != "" {
+			idx.Add("org:"+org, d.DomainName)
+		}
+	}
+	return idx
+}
+
+// GetCertOrgDomains links the domain to every indexed domain whose certificate names the same organization.
+// Organizations on more than MaxCertOrgDomains domains are skipped
+func (d *Domain) GetCertOrgDomains(idx DomainIndex, n OrgNormalization) {
+	domsFound := make(map[string]CertOrgDomain)
+	for _, df := range d.CertOrgDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	org := d.CertOrganization(n)
+	if matched := idx.Lookup("org:" + org); org != "" && len(matched) <= MaxCertOrgDomains {
+		for _, dn := range matched {
+			if dn == d.DomainName {
+				continue
+			}
+			if df, exists := domsFound[dn]; !exists {
+				domsFound[dn] = CertOrgDomain{
+					MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dn}, Organization: org,
+				}
+			} else {
+				df.UpdatedAt = now
+				df.Organization = org
+				domsFound[dn] = df
+			}
+		}
+	}
+	var cd []CertOrgDomain
+	for _, df := range domsFound {
+		cd = append(cd, df)
+	}
+	d.CertOrgDomains = cd
+}
@@ -0,0 +1,66 @@
+package domains
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrgNormalization(t *testing.T) {
+	for _, tt := range []struct {
+		n    OrgNormalization
+		org  string
+		want string
+	}{
+		{OrgNormalization{}, "Acme Corp, Inc.", "acme"},
+		{OrgNormalization{}, "ACME CORP", "acme"},
+		{OrgNormalization{}, "O'Brien Holdings L.L.C.", "obrien holdings"},
+		{OrgNormalization{}, "Inc.", "inc"},
+		{OrgNormalization{CaseSensitive: true}, "Acme Corp, Inc.", "Acme"},
+		{OrgNormalization{KeepPunctuation: true}, "Acme-Widgets, Inc.", "acme-widgets"},
+		{OrgNormalization{Suffixes: []string{"holdings"}}, "Acme Corp Holdings", "acme corp"},
+	} {
+		got := tt.n.Normalize(tt.org)
+		if got != tt.want {
+			t.Errorf("%+v Normalize(%q) = %q, want %q", tt.n, tt.org, got, tt.want)
+		}
+		if !strings.HasPrefix(OrgKey(tt.n.Normalize(tt.org)), OrgKey(got)) {
+			t.Errorf("OrgKey(%q) is not a prefix", got)
+		}
+	}
+}
+
+func TestGetCertOrgDomains(t *testing.T) {
+	newDom := func(name string, cert *Certificate) *Domain {
+		d, err := NewDomain(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Certificate = cert
+		return d
+	}
+	acme := newDom("acme.com", &Certificate{SubjectOrganization: "Acme Corp, Inc.", ChainValid: true})
+	widgets := newDom("acme-widgets.com", &Certificate{SubjectOrganization: "ACME CORP", ChainValid: true})
+	selfSigned := newDom("fake-acme.com", &Certificate{SubjectOrganization: "Acme Corp", SelfSigned: true})
+	dv := newDom("other.com", &Certificate{ChainValid: true})
+
+	n := OrgNormalization{}
+	idx := NewCertOrgIndex([]*Domain{acme, widgets, selfSigned, dv}, n)
+	acme.GetCertOrgDomains(idx, n)
+	if len(acme.CertOrgDomains) != 1 || acme.CertOrgDomains[0].DomainName != "acme-widgets.com" ||
+		acme.CertOrgDomains[0].Organization != "acme" {
+		t.Errorf("CertOrgDomains = %v, want only acme-widgets.com", acme.CertOrgDomains)
+	}
+	selfSigned.GetCertOrgDomains(idx, n)
+	if len(selfSigned.CertOrgDomains) != 0 {
+		t.Errorf("CertOrgDomains = %v, want none for a self-signed certificate", selfSigned.CertOrgDomains)
+	}
+
+	old := MaxCertOrgDomains
+	MaxCertOrgDomains = 1
+	defer func() { MaxCertOrgDomains = old }()
+	acme.CertOrgDomains = nil
+	acme.GetCertOrgDomains(idx, n)
+	if len(acme.CertOrgDomains) != 0 {
+		t.Errorf("CertOrgDomains = %v, want organizations over the limit skipped", acme.CertOrgDomains)
+	}
+}
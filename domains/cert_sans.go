@@ -1,66 +1,218 @@
 package domains
 
 import (
+	"bufio"
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
 	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type CertSansDomain struct {
 	MatchedDomain
+	// Endpoints are the host:port probes whose certificate listed the domain
+	Endpoints []string `json:"endpoints,omitempty"`
 }
 
-func (d *Domain) GetCertSANs() error {
-	d.LastRanCertSans = time.Now()
-	dom := d.DomainName
-	dialer := net.Dialer{Timeout: 3 * time.Second}
-	// Use the dialTLS function to connect
-	conn, err := dialer.Dial(
-		"tcp", dom+":443",
-	)
-	if err != nil {
+var (
+	// CertProbeHosts are the labels, relative to the domain, probed for certificates. "" is the apex
+	CertProbeHosts = []string{"", "www"}
+	// CertProbePorts are the ports probed on each of CertProbeHosts
+	CertProbePorts = []int{443}
+	// StartTLSPorts maps ports to the protocol used to upgrade to TLS, other ports are TLS from the start
+	StartTLSPorts = map[int]string{25: "smtp", 587: "smtp", 143: "imap"}
+)
+
+const certDialTimeout = 3 * time.Second
+
+// certDial connects to a probe endpoint
+var certDial = func(ctx context.Context, addr string) (net.Conn, error) {
+	return (&net.Dialer{Timeout: certDialTimeout}).DialContext(ctx, "tcp", addr)
+}
+
+func (d *Domain) certProbeHosts() []string {
+	if d.config != nil && len(d.config.CertProbeHosts) > 0 {
+		return d.config.CertProbeHosts
+	}
+	return CertProbeHosts
+}
+
+func (d *Domain) certProbePorts() []int {
+	if d.config != nil && len(d.config.CertProbePorts) > 0 {
+		return d.config.CertProbePorts
+	}
+	return CertProbePorts
+}
+
+// startSMTPTLS issues the SMTP STARTTLS command (RFC 3207)
+func startSMTPTLS(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+	if _, _, err := tp.ReadResponse(220); err != nil {
 		return err
 	}
-	defer conn.Close()
-	tlsConn := tls.Client(
-		conn, &tls.Config{
-			ServerName:         dom,
-			InsecureSkipVerify: true,
-		},
-	)
-	err = tlsConn.Handshake()
+	for _, step := range []struct {
+		cmd  string
+		code int
+	}{{"EHLO domwalk", 250}, {"STARTTLS", 220}} {
+		if _, err := tp.Cmd(step.cmd); err != nil {
+			return err
+		}
+		if _, _, err := tp.ReadResponse(step.code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startIMAPTLS issues the IMAP STARTTLS command (RFC 3501 section 6.2.1)
+func startIMAPTLS(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	greeting, err := r.ReadString('\n')
 	if err != nil {
 		return err
 	}
-	defer tlsConn.Close()
-	cert := tlsConn.ConnectionState().PeerCertificates[0]
-	now := time.Now()
-	domsFound := make(map[string]CertSansDomain)
-	for _, df := range d.CertSANs {
-		domsFound[df.DomainName] = df
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("Unexpected IMAP greeting: %s", strings.TrimSpace(greeting))
 	}
-	for _, san := range cert.DNSNames {
-		dm, err := NewDomain(san)
+	if _, err := conn.Write([]byte("a1 STARTTLS\r\n")); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			if !strings.HasPrefix(line, "a1 OK") {
+				return fmt.Errorf("IMAP STARTTLS refused: %s", strings.TrimSpace(line))
+			}
+			return nil
+		}
+	}
+}
+
+// sanDomains returns the distinct registrable domains of a certificate's names, other than self, and the
+// names seen under each
+func sanDomains(names []string, self string) ([]string, map[string][]string) {
+	hosts := make(map[string][]string)
+	var doms []string
+	for _, san := range names {
+		dm, err := NewDomain(strings.TrimPrefix(san, "*."))
 		if err != nil {
 			log.Println("Error parsing domain: ", err)
 			continue
 		}
-		if dm.DomainName == dom {
+		if dm.DomainName == self {
 			continue
 		}
-		if c, exists := domsFound[dm.DomainName]; !exists {
-			certSAN := CertSansDomain{MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dm.DomainName}}
-			domsFound[dm.DomainName] = certSAN
-		} else {
-			c.UpdatedAt = now
-			domsFound[dm.DomainName] = c
+		if _, seen := hosts[dm.DomainName]; !seen {
+			doms = append(doms, dm.DomainName)
+		}
+		hosts[dm.DomainName] = append(hosts[dm.DomainName], dm.FQDN())
+	}
+	return doms, hosts
+}
+
+// probeCertificate returns the certificates presented by host on port, upgrading with STARTTLS where needed
+func probeCertificate(ctx context.Context, host string, port int) (*tls.ConnectionState, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := certDial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer watchConn(ctx, conn, 2*certDialTimeout)()
+	// Verification is done separately by newCertificate so that invalid chains are still recorded
+	cfg := &tls.Config{ServerName: host, InsecureSkipVerify: true}
+	switch StartTLSPorts[port] {
+	case "smtp":
+		if err := startSMTPTLS(conn); err != nil {
+			return nil, err
+		}
+	case "imap":
+		if err := startIMAPTLS(conn); err != nil {
+			return nil, err
+		}
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	state := tlsConn.ConnectionState()
+	return &state, nil
+}
+
+// GetCertSANsContext probes each of the configured hosts and ports, recording the certificate of the first endpoint
+// to answer and merging the SANs of all of them. Endpoints that failed are returned joined, even when others answered
+func (d *Domain) GetCertSANsContext(ctx context.Context) error {
+	d.LastRanCertSans = time.Now()
+	dom := d.DomainName
+	now := time.Now()
+	domsFound := make(map[string]CertSansDomain)
+	for _, df := range d.CertSANs {
+		df.Endpoints = nil
+		domsFound[df.DomainName] = df
+	}
+	var certificate *Certificate
+	var errs []error
+probes:
+	for _, label := range d.certProbeHosts() {
+		host := dom
+		if label != "" {
+			host = label + "." + dom
+		}
+		for _, port := range d.certProbePorts() {
+			endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+			if err := ctx.Err(); err != nil {
+				errs = append(errs, err)
+				break probes
+			}
+			state, err := probeCertificate(ctx, host, port)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (%w)", endpoint, err))
+				continue
+			}
+			if len(state.PeerCertificates) == 0 {
+				errs = append(errs, fmt.Errorf("%s (no certificate presented)", endpoint))
+				continue
+			}
+			if certificate == nil {
+				certificate = newCertificate(state.PeerCertificates, host)
+				certificate.Endpoint = endpoint
+			}
+			doms, hosts := sanDomains(state.PeerCertificates[0].DNSNames, dom)
+			for _, dn := range doms {
+				c, exists := domsFound[dn]
+				if !exists {
+					c = CertSansDomain{MatchedDomain: MatchedDomain{CreatedAt: now, DomainName: dn}}
+				}
+				c.UpdatedAt = now
+				c.Endpoints = append(c.Endpoints, endpoint)
+				for _, h := range hosts[dn] {
+					c.AddHost(h)
+				}
+				domsFound[dn] = c
+			}
 		}
 	}
+	if certificate == nil {
+		return fmt.Errorf("Failed to probe all endpoints: %w", errors.Join(errs...))
+	}
+	if d.Certificate != nil && d.Certificate.FingerprintSHA256 == certificate.FingerprintSHA256 {
+		certificate.CreatedAt = d.Certificate.CreatedAt
+	}
+	d.Certificate = certificate
 	var cs []CertSansDomain
 	for _, c := range domsFound {
 		cs = append(cs, c)
 	}
 	d.CertSANs = cs
-	return nil
+	// The SANs found are kept, the endpoints that failed are reported alongside them
+	return errors.Join(errs...)
 }
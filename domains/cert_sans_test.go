@@ -0,0 +1,137 @@
+package domains
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveTLSProbe accepts connections on a local port, running greet before the TLS handshake
+func serveTLSProbe(t *testing.T, cert tls.Certificate, greet func(conn net.Conn, r *bufio.Reader)) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if greet != nil {
+					greet(conn, bufio.NewReader(conn))
+				}
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+				tlsConn.Handshake()
+				tlsConn.Close()
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func smtpGreeting(conn net.Conn, r *bufio.Reader) {
+	conn.Write([]byte("220 mx.test ESMTP\r\n"))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"):
+			conn.Write([]byte("250-mx.test\r\n250 STARTTLS\r\n"))
+		case cmd == "STARTTLS":
+			conn.Write([]byte("220 Ready to start TLS\r\n"))
+			return
+		default:
+			conn.Write([]byte("502 Unrecognised command\r\n"))
+		}
+	}
+}
+
+func imapGreeting(conn net.Conn, r *bufio.Reader) {
+	conn.Write([]byte("* OK IMAP4rev1 ready\r\n"))
+	if line, _ := r.ReadString('\n'); strings.HasSuffix(strings.TrimSpace(line), "STARTTLS") {
+		conn.Write([]byte("a1 OK Begin TLS negotiation now\r\n"))
+	}
+}
+
+func TestGetCertSANsProbes(t *testing.T) {
+	webCert, webPool := newTestCert(t, "Acme Corp", "acme.com", "www.acme.com", "acme-widgets.com")
+	smtpCert, _ := newTestCert(t, "Acme Corp", "mail.acme.com", "acme-mail.net")
+	imapCert, _ := newTestCert(t, "Acme Corp", "mail.acme.com", "acme-imap.org")
+	httpsPort := serveTLSProbe(t, webCert, nil)
+	smtpPort := serveTLSProbe(t, smtpCert, smtpGreeting)
+	imapPort := serveTLSProbe(t, imapCert, imapGreeting)
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	origDial, origStartTLS := certDial, StartTLSPorts
+	t.Cleanup(
+		func() {
+			certDial, StartTLSPorts, CertRoots = origDial, origStartTLS, nil
+		},
+	)
+	// Every host resolves to the local servers
+	certDial = func(ctx context.Context, addr string) (net.Conn, error) {
+		_, port, _ := net.SplitHostPort(addr)
+		return net.DialTimeout("tcp", "127.0.0.1:"+port, time.Second)
+	}
+	StartTLSPorts = map[int]string{smtpPort: "smtp", imapPort: "imap"}
+	CertRoots = webPool
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{
+		CertProbeHosts: []string{"", "mail"}, CertProbePorts: []int{httpsPort, smtpPort, imapPort, closedPort},
+	}
+	// The closed port is reported, the SANs of the other endpoints are kept
+	if err := d.GetCertSANs(); err == nil || !strings.Contains(err.Error(), strconv.Itoa(closedPort)) {
+		t.Errorf("expected an error naming the closed port, got %v", err)
+	}
+
+	endpoints := make(map[string][]string)
+	for _, c := range d.CertSANs {
+		endpoints[c.DomainName] = c.Endpoints
+	}
+	ep := func(host string, port int) string { return net.JoinHostPort(host, strconv.Itoa(port)) }
+	want := map[string][]string{
+		"acme-widgets.com": {ep("acme.com", httpsPort), ep("mail.acme.com", httpsPort)},
+		"acme-mail.net":    {ep("acme.com", smtpPort), ep("mail.acme.com", smtpPort)},
+		"acme-imap.org":    {ep("acme.com", imapPort), ep("mail.acme.com", imapPort)},
+	}
+	if len(endpoints) != len(want) {
+		t.Errorf("CertSANs = %v, want %v", endpoints, want)
+	}
+	for dn, w := range want {
+		got := endpoints[dn]
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(w, ",") {
+			t.Errorf("%s endpoints = %v, want %v", dn, got, w)
+		}
+	}
+	if d.Certificate == nil || d.Certificate.Endpoint != ep("acme.com", httpsPort) || !d.Certificate.ChainValid {
+		t.Errorf("Certificate = %+v, want the valid apex certificate", d.Certificate)
+	}
+
+	d.config.CertProbePorts = []int{closedPort}
+	err = d.GetCertSANs()
+	if err == nil || !strings.Contains(err.Error(), strconv.Itoa(closedPort)) {
+		t.Errorf("expected an error naming the closed port, got %v", err)
+	}
+}
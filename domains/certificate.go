@@ -0,0 +1,113 @@
+package domains
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Certificate is the leaf presented by the first answering probe endpoint
+type Certificate struct {
+	CreatedAt           time.Time          `json:"createdAt,omitempty"`
+	UpdatedAt           time.Time          `json:"updatedAt,omitempty"`
+	Endpoint            string             `json:"endpoint,omitempty"`
+	Issuer              string             `json:"issuer,omitempty"`
+	IssuerOrganization  string             `json:"issuerOrganization,omitempty"`
+	SubjectCommonName   string             `json:"subjectCommonName,omitempty"`
+	SubjectOrganization string             `json:"subjectOrganization,omitempty"`
+	Serial              string             `json:"serial,omitempty"`
+	FingerprintSHA256   string             `json:"fingerprintSHA256,omitempty"`
+	NotBefore           time.Time          `json:"notBefore,omitempty"`
+	NotAfter            time.Time          `json:"notAfter,omitempty"`
+	KeyType             string             `json:"keyType,omitempty"`
+	SelfSigned          bool               `json:"selfSigned"`
+	ChainValid          bool               `json:"chainValid"`
+	VerifyError         string             `json:"verifyError,omitempty"`
+	Chain               []CertificateChain `json:"chain,omitempty"`
+}
+
+// CertificateChain is one certificate presented after the leaf
+type CertificateChain struct {
+	Subject           string    `json:"subject,omitempty"`
+	Issuer            string    `json:"issuer,omitempty"`
+	FingerprintSHA256 string    `json:"fingerprintSHA256,omitempty"`
+	NotBefore         time.Time `json:"notBefore,omitempty"`
+	NotAfter          time.Time `json:"notAfter,omitempty"`
+}
+
+// CertRoots overrides the system roots used to verify certificate chains
+var CertRoots *x509.CertPool
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", pub.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + pub.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+// Expired reports whether the certificate is outside its validity period
+func (c *Certificate) Expired() bool {
+	now := time.Now()
+	return now.Before(c.NotBefore) || now.After(c.NotAfter)
+}
+
+// newCertificate records the leaf of a presented chain, verifying the chain for host against CertRoots
+func newCertificate(chain []*x509.Certificate, host string) *Certificate {
+	leaf := chain[0]
+	now := time.Now()
+	c := &Certificate{
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Issuer:            leaf.Issuer.String(),
+		SubjectCommonName: leaf.Subject.CommonName,
+		Serial:            leaf.SerialNumber.Text(16),
+		FingerprintSHA256: fingerprint(leaf),
+		NotBefore:         leaf.NotBefore,
+		NotAfter:          leaf.NotAfter,
+		KeyType:           keyType(leaf),
+		SelfSigned: bytes.Equal(leaf.RawIssuer, leaf.RawSubject) &&
+			leaf.CheckSignatureFrom(leaf) == nil,
+	}
+	if len(leaf.Issuer.Organization) > 0 {
+		c.IssuerOrganization = strings.Join(leaf.Issuer.Organization, ", ")
+	}
+	if len(leaf.Subject.Organization) > 0 {
+		c.SubjectOrganization = strings.Join(leaf.Subject.Organization, ", ")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+		c.Chain = append(
+			c.Chain, CertificateChain{
+				Subject:           cert.Subject.String(),
+				Issuer:            cert.Issuer.String(),
+				FingerprintSHA256: fingerprint(cert),
+				NotBefore:         cert.NotBefore,
+				NotAfter:          cert.NotAfter,
+			},
+		)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: CertRoots, Intermediates: intermediates})
+	c.ChainValid = err == nil
+	if err != nil {
+		c.VerifyError = err.Error()
+	}
+	return c
+}
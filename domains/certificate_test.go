@@ -0,0 +1,33 @@
+package domains
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestNewCertificate(t *testing.T) {
+	cert, pool := newTestCert(t, "Acme Corp", "acme-corp.com", "www.acme-corp.com")
+	chain := []*x509.Certificate{cert.Leaf}
+
+	untrusted := newCertificate(chain, "acme-corp.com")
+	if untrusted.ChainValid || untrusted.VerifyError == "" {
+		t.Errorf("expected an untrusted chain, got %+v", untrusted)
+	}
+	if !untrusted.SelfSigned || untrusted.SubjectOrganization != "Acme Corp" ||
+		untrusted.KeyType != "ECDSA-P-256" || len(untrusted.FingerprintSHA256) != 64 {
+		t.Errorf("unexpected certificate metadata %+v", untrusted)
+	}
+
+	CertRoots = pool
+	t.Cleanup(func() { CertRoots = nil })
+	trusted := newCertificate(chain, "www.acme-corp.com")
+	if !trusted.ChainValid || trusted.VerifyError != "" {
+		t.Errorf("expected a valid chain, got %q", trusted.VerifyError)
+	}
+	if wrongHost := newCertificate(chain, "other.com"); wrongHost.ChainValid {
+		t.Error("expected verification to fail for a host not on the certificate")
+	}
+	if trusted.Expired() {
+		t.Error("certificate should not be expired")
+	}
+}
@@ -0,0 +1,198 @@
+package domains
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+// ProviderSignature names a provider and the host suffixes that identify it. Labels in a suffix may contain *
+type ProviderSignature struct {
+	Name string   `json:"name"`
+	MX   []string `json:"mx,omitempty"`
+	SPF  []string `json:"spf,omitempty"`
+	NS   []string `json:"ns,omitempty"`
+}
+
+// ProviderSignatures are checked in order, so mail gateways are listed before the mailbox providers behind them
+type ProviderSignatures struct {
+	MailProviders []ProviderSignature `json:"mail_providers"`
+	DNSHosts      []ProviderSignature `json:"dns_hosts"`
+}
+
+const (
+	ProviderSelfHosted = "Self-hosted"
+	ProviderOther      = "Other"
+	ProviderNone       = "None"
+)
+
+//go:embed signatures.json
+var defaultSignatures []byte
+
+// Signatures classify mail providers and DNS hosts. They are read from the file named by DOMWALK_SIGNATURES,
+// else the embedded signatures.json
+var Signatures *ProviderSignatures
+
+func ParseSignatures(data []byte) (*ProviderSignatures, error) {
+	sigs := &ProviderSignatures{}
+	if err := json.Unmarshal(data, sigs); err != nil {
+		return nil, err
+	}
+	return sigs, nil
+}
+
+func init() {
+	var err error
+	if p := os.Getenv("DOMWALK_SIGNATURES"); p != "" {
+		var data []byte
+		if data, err = os.ReadFile(p); err == nil {
+			if Signatures, err = ParseSignatures(data); err == nil {
+				return
+			}
+		}
+		log.Printf("Error loading signatures from %s, using the defaults: %s\n", p, err)
+	}
+	if Signatures, err = ParseSignatures(defaultSignatures); err != nil {
+		panic(err)
+	}
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+// matchHost reports whether host equals, or is a subdomain of, one of the suffix patterns. Wildcard patterns
+// match the host's registrable domain only: a trailing ".*" stands for any public suffix, and other wildcards
+// don't cross dots, so "azure-dns.*" matches ns1-01.azure-dns.com but not azure-dns.evil.example
+func matchHost(host string, patterns []string) bool {
+	host = normalizeHost(host)
+	var registrable *Domain
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			if host == pattern || strings.HasSuffix(host, "."+pattern) {
+				return true
+			}
+			continue
+		}
+		if registrable == nil {
+			dom, err := NewDomain(host)
+			if err != nil || dom.NonPublicDomain {
+				continue
+			}
+			registrable = dom
+		}
+		if p, ok := strings.CutSuffix(pattern, ".*"); ok {
+			pattern = p + "." + registrable.Suffix
+		}
+		if strings.Count(pattern, ".") != strings.Count(registrable.DomainName, ".") {
+			continue
+		}
+		if matched, _ := path.Match(pattern, registrable.DomainName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MailProviderForMX returns the first provider, in signature order, running any of the mail exchangers
+func (s *ProviderSignatures) MailProviderForMX(hosts ...string) string {
+	for _, p := range s.MailProviders {
+		for _, h := range hosts {
+			if matchHost(h, p.MX) {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+func (s *ProviderSignatures) MailProviderForSPF(includes ...string) string {
+	for _, p := range s.MailProviders {
+		for _, h := range includes {
+			if matchHost(h, p.SPF) {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+func (s *ProviderSignatures) DNSHostForNS(hosts ...string) string {
+	for _, p := range s.DNSHosts {
+		for _, h := range hosts {
+			if matchHost(h, p.NS) {
+				return p.Name
+			}
+		}
+	}
+	return ""
+}
+
+// ownHost reports whether host is under the domain itself
+func (d *Domain) ownHost(host string) bool {
+	host = normalizeHost(host)
+	return host == d.DomainName || strings.HasSuffix(host, "."+d.DomainName)
+}
+
+// ClassifyMailProvider classifies the domain's mail provider from its MX records, falling back to its
+// SPF includes when the mail exchangers aren't recognised
+func (d *Domain) ClassifyMailProvider() string {
+	var hosts []string
+	for _, m := range d.MXRecords {
+		// A null MX (RFC 7505) accepts no mail
+		if host := normalizeHost(m.Mx); host != "" && host != "localhost" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return ProviderNone
+	}
+	if p := Signatures.MailProviderForMX(hosts...); p != "" {
+		return p
+	}
+	var includes []string
+	for _, s := range d.SpfIncludeDomains {
+		includes = append(includes, s.Include)
+	}
+	if p := Signatures.MailProviderForSPF(includes...); p != "" {
+		return p
+	}
+	for _, host := range hosts {
+		if d.ownHost(host) {
+			return ProviderSelfHosted
+		}
+	}
+	return ProviderOther
+}
+
+// ClassifyDNSHost classifies the domain's DNS host from its NS records
+func (d *Domain) ClassifyDNSHost() string {
+	if len(d.NSRecords) == 0 {
+		return ProviderNone
+	}
+	var hosts []string
+	for _, n := range d.NSRecords {
+		hosts = append(hosts, n.NS)
+	}
+	if p := Signatures.DNSHostForNS(hosts...); p != "" {
+		return p
+	}
+	for _, n := range d.NSRecords {
+		if d.ownHost(n.NS) {
+			return ProviderSelfHosted
+		}
+	}
+	return ProviderOther
+}
+
+// Classify sets MailProvider and DNSHost from the domain's DNS and SPF data
+func (d *Domain) Classify() {
+	if d.LastRanDns.IsZero() {
+		return
+	}
+	d.MailProvider = d.ClassifyMailProvider()
+	d.DNSHost = d.ClassifyDNSHost()
+}
@@ -0,0 +1,97 @@
+package domains
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		patterns []string
+		want     bool
+	}{
+		{"lara.ns.cloudflare.com.", []string{"cloudflare.com"}, true},
+		{"cloudflare.com", []string{"cloudflare.com"}, true},
+		{"evilcloudflare.com", []string{"cloudflare.com"}, false},
+		{"ns1-01.azure-dns.com.", []string{"azure-dns.*"}, true},
+		{"ns3-01.azure-dns.org", []string{"azure-dns.*"}, true},
+		{"azure-dns.evil.example", []string{"azure-dns.*"}, false},
+		{"ns1.azure-dns.evil.com", []string{"azure-dns.*"}, false},
+		{"ns-12.awsdns-01.co.uk.", []string{"awsdns-[0-9][0-9].*"}, true},
+		{"ns-12.awsdns-evil.com", []string{"awsdns-[0-9][0-9].*"}, false},
+		{"ns1.awsdns-01.evil.com", []string{"awsdns-[0-9][0-9].*"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchHost(tt.host, tt.patterns); got != tt.want {
+			t.Errorf("matchHost(%q, %v) = %t, want %t", tt.host, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name         string
+		mx           []string
+		ns           []string
+		spf          []string
+		mailProvider string
+		dnsHost      string
+	}{
+		{
+			name: "acme.com", mx: []string{"acme-com.mail.protection.outlook.com."},
+			ns: []string{"ns-12.awsdns-01.co.uk.", "ns-99.awsdns-33.com."}, mailProvider: "Microsoft 365", dnsHost: "Route53",
+		},
+		{
+			name: "gateway.com", mx: []string{"aspmx.l.google.com.", "mx0a-001.pphosted.com."},
+			ns: []string{"lara.ns.cloudflare.com."}, mailProvider: "Proofpoint", dnsHost: "Cloudflare",
+		},
+		{
+			name: "onprem.com", mx: []string{"mail.onprem.com."}, ns: []string{"ns1.onprem.com."},
+			mailProvider: ProviderSelfHosted, dnsHost: ProviderSelfHosted,
+		},
+		{
+			name: "relay.com", mx: []string{"mx.relayhost.net."}, spf: []string{"_spf.google.com"},
+			mailProvider: "Google Workspace", dnsHost: ProviderNone,
+		},
+		{
+			name: "nomail.com", mx: []string{"."}, ns: []string{"ns1.smallhost.net."},
+			mailProvider: ProviderNone, dnsHost: ProviderOther,
+		},
+	}
+	for _, tt := range tests {
+		d, err := NewDomain(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.LastRanDns = time.Now()
+		for _, mx := range tt.mx {
+			d.MXRecords = append(d.MXRecords, MXRecord{Mx: mx})
+		}
+		for _, ns := range tt.ns {
+			d.NSRecords = append(d.NSRecords, NSRecord{NS: ns})
+		}
+		for _, inc := range tt.spf {
+			d.SpfIncludeDomains = append(d.SpfIncludeDomains, SpfIncludeDomain{Include: inc})
+		}
+		d.Classify()
+		if d.MailProvider != tt.mailProvider || d.DNSHost != tt.dnsHost {
+			t.Errorf(
+				"%s: got %q/%q, want %q/%q", tt.name, d.MailProvider, d.DNSHost, tt.mailProvider, tt.dnsHost,
+			)
+		}
+	}
+}
+
+func TestParseSignatures(t *testing.T) {
+	sigs, err := ParseSignatures([]byte(`{"dns_hosts": [{"name": "Acme DNS", "ns": ["acmedns.*"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sigs.DNSHostForNS("ns1.acmedns.co.uk."); got != "Acme DNS" {
+		t.Errorf("DNSHostForNS = %q, want Acme DNS", got)
+	}
+	if _, err := ParseSignatures([]byte(`{`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
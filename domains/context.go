@@ -0,0 +1,138 @@
+package domains
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// watchConn sets conn's deadline to the earlier of timeout from now and ctx's deadline, and unblocks any
+// pending read or write when ctx is done. The returned func stops watching
+func watchConn(ctx context.Context, conn net.Conn, timeout time.Duration) func() bool {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	return context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+}
+
+// GetDNSRecords is GetDNSRecordsContext with the background context
+func (d *Domain) GetDNSRecords() []error {
+	return d.GetDNSRecordsContext(context.Background())
+}
+
+// GetRedirectDomains is GetRedirectDomainsContext with the background context
+func (d *Domain) GetRedirectDomains() error {
+	return d.GetRedirectDomainsContext(context.Background())
+}
+
+// GetCertSANs is GetCertSANsContext with the background context
+func (d *Domain) GetCertSANs() error {
+	return d.GetCertSANsContext(context.Background())
+}
+
+// GetDomainsFromSitemap is GetDomainsFromSitemapContext with the background context
+func (d *Domain) GetDomainsFromSitemap() error {
+	return d.GetDomainsFromSitemapContext(context.Background())
+}
+
+// GetHomepageLinkDomains is GetHomepageLinkDomainsContext with the background context
+func (d *Domain) GetHomepageLinkDomains() error {
+	return d.GetHomepageLinkDomainsContext(context.Background())
+}
+
+// GetTrackerIDs is GetTrackerIDsContext with the background context
+func (d *Domain) GetTrackerIDs() error {
+	return d.GetTrackerIDsContext(context.Background())
+}
+
+// GetStructuredData is GetStructuredDataContext with the background context
+func (d *Domain) GetStructuredData() error {
+	return d.GetStructuredDataContext(context.Background())
+}
+
+// GetWhoisData is GetWhoisDataContext with the background context
+func (d *Domain) GetWhoisData() error {
+	return d.GetWhoisDataContext(context.Background())
+}
+
+// GetSPFIncludeDomains is GetSPFIncludeDomainsContext with the background context
+func (d *Domain) GetSPFIncludeDomains() error {
+	return d.GetSPFIncludeDomainsContext(context.Background())
+}
+
+// GetDmarcReportDomains is GetDmarcReportDomainsContext with the background context
+func (d *Domain) GetDmarcReportDomains() error {
+	return d.GetDmarcReportDomainsContext(context.Background())
+}
+
+// GetCTLogDomains is GetCTLogDomainsContext with the background context
+func (d *Domain) GetCTLogDomains() error {
+	return d.GetCTLogDomainsContext(context.Background())
+}
+
+// QueryMX is QueryMXContext with the background context
+func (d *Domain) QueryMX() error {
+	return d.QueryMXContext(context.Background())
+}
+
+// QueryA is QueryAContext with the background context
+func (d *Domain) QueryA() error {
+	return d.QueryAContext(context.Background())
+}
+
+// QueryAAAA is QueryAAAAContext with the background context
+func (d *Domain) QueryAAAA() error {
+	return d.QueryAAAAContext(context.Background())
+}
+
+// QuerySOA is QuerySOAContext with the background context
+func (d *Domain) QuerySOA() error {
+	return d.QuerySOAContext(context.Background())
+}
+
+// QueryNS is QueryNSContext with the background context
+func (d *Domain) QueryNS() error {
+	return d.QueryNSContext(context.Background())
+}
+
+// QueryTXT is QueryTXTContext with the background context
+func (d *Domain) QueryTXT() error {
+	return d.QueryTXTContext(context.Background())
+}
+
+// QueryCNAME is QueryCNAMEContext with the background context
+func (d *Domain) QueryCNAME() error {
+	return d.QueryCNAMEContext(context.Background())
+}
+
+// QueryCAA is QueryCAAContext with the background context
+func (d *Domain) QueryCAA() error {
+	return d.QueryCAAContext(context.Background())
+}
+
+// QuerySRV is QuerySRVContext with the background context
+func (d *Domain) QuerySRV() error {
+	return d.QuerySRVContext(context.Background())
+}
+
+// QueryPTR is QueryPTRContext with the background context
+func (d *Domain) QueryPTR() error {
+	return d.QueryPTRContext(context.Background())
+}
+
+// QueryDmarc is QueryDmarcContext with the background context
+func (d *Domain) QueryDmarc() error {
+	return d.QueryDmarcContext(context.Background())
+}
+
+// QueryDKIM is QueryDKIMContext with the background context
+func (d *Domain) QueryDKIM() error {
+	return d.QueryDKIMContext(context.Background())
+}
+
+// GetContactDomainsFromSitemap is GetContactDomainsFromSitemapContext with the background context
+func (d *Domain) GetContactDomainsFromSitemap() error {
+	return d.GetContactDomainsFromSitemapContext(context.Background())
+}
@@ -0,0 +1,81 @@
+package domains
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGetCertSANsContext(t *testing.T) {
+	// Accepts connections but never answers the TLS handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	origDial := certDial
+	defer func() { certDial = origDial }()
+	certDial = func(ctx context.Context, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", l.Addr().String())
+	}
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = d.GetCertSANsContext(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetCertSANsContext returned after %s, want it to stop at the deadline", elapsed)
+	}
+	if err == nil || ClassifyError(err) != ErrorClassTimeout {
+		t.Errorf("GetCertSANsContext() = %v, want a timeout error", err)
+	}
+}
+
+func TestEnrichContext(t *testing.T) {
+	// Never answers queries
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dead.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	d.Enrich(
+		EnrichmentConfig{
+			DNS: true, Resolvers: []string{dead.LocalAddr().String()}, DNSTimeout: 10 * time.Second,
+			DomainTimeout: 200 * time.Millisecond,
+		},
+	)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Enrich returned after %s, want it to stop at the domain timeout", elapsed)
+	}
+	if len(d.EnrichmentErrors) != 1 || d.EnrichmentErrors[0].Class != ErrorClassTimeout {
+		t.Errorf("EnrichmentErrors = %+v, want a DNS timeout", d.EnrichmentErrors)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d, _ = NewDomain("acme.com")
+	d.EnrichContext(ctx, EnrichmentConfig{DNS: true, Resolvers: []string{dead.LocalAddr().String()}})
+	if !d.LastRanDns.IsZero() {
+		t.Error("EnrichContext ran DNS with a canceled context")
+	}
+}
@@ -0,0 +1,381 @@
+package domains
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CTLogDomain struct {
+	MatchedDomain
+	// FirstSeen and LastSeen span the validity of the logged certificates naming both domains
+	FirstSeen time.Time `json:"firstSeen,omitempty"`
+	LastSeen  time.Time `json:"lastSeen,omitempty"`
+}
+
+// CTEntry is one logged certificate or precertificate
+type CTEntry struct {
+	ID        int64     `json:"id,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	Names     []string  `json:"names,omitempty"`
+	NotBefore time.Time `json:"notBefore,omitempty"`
+	NotAfter  time.Time `json:"notAfter,omitempty"`
+}
+
+// CTSource looks up the logged certificates covering a domain or its subdomains
+type CTSource interface {
+	Certificates(ctx context.Context, domain string) ([]CTEntry, error)
+}
+
+// DefaultCTSource is used when no CT source is configured. {domain} is replaced by the domain name. The wildcard
+// only matches subdomains, so the domain itself is queried too
+var DefaultCTSource = "https://crt.sh/?q=%25.{domain}&output=json"
+
+// ctWildcard is the URL-escaped %. prefix matching every subdomain in a crt.sh query
+const ctWildcard = "%25.{domain}"
+
+// MaxCTCertificates caps the certificates downloaded per domain from a crt.sh-compatible endpoint, newest first
+var MaxCTCertificates = 50
+
+// crtShTimeLayout is the zone-less UTC layout of crt.sh JSON timestamps
+const crtShTimeLayout = "2006-01-02T15:04:05"
+
+type crtShEntry struct {
+	ID        int64  `json:"id"`
+	Issuer    string `json:"issuer_name"`
+	Common    string `json:"common_name"`
+	NameValue string `json:"name_value"`
+	NotBefore string `json:"not_before"`
+	NotAfter  string `json:"not_after"`
+}
+
+func (e crtShEntry) entry() CTEntry {
+	ct := CTEntry{ID: e.ID, Issuer: e.Issuer}
+	for _, name := range strings.Fields(e.NameValue + " " + e.Common) {
+		ct.Names = append(ct.Names, strings.ToLower(name))
+	}
+	ct.NotBefore, _ = time.Parse(crtShTimeLayout, e.NotBefore)
+	ct.NotAfter, _ = time.Parse(crtShTimeLayout, e.NotAfter)
+	return ct
+}
+
+// decodeCrtSh reads a crt.sh JSON array, merging the rows crt.sh returns per matching identity of a certificate
+func decodeCrtSh(r io.Reader) ([]CTEntry, error) {
+	var rows []crtShEntry
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("error parsing CT JSON: %v", err)
+	}
+	var entries []CTEntry
+	for _, row := range rows {
+		entries = append(entries, row.entry())
+	}
+	return mergeCTEntries(entries), nil
+}
+
+// mergeCTEntries merges the names of entries with the same crt.sh id
+func mergeCTEntries(entries []CTEntry) []CTEntry {
+	var merged []CTEntry
+	byID := make(map[int64]int)
+	for _, e := range entries {
+		if i, ok := byID[e.ID]; ok && e.ID != 0 {
+			merged[i].Names = append(merged[i].Names, e.Names...)
+			continue
+		}
+		byID[e.ID] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// CrtShSource queries a crt.sh-compatible JSON endpoint. crt.sh only lists the names matching the query, so
+// when CertURL is set each certificate is downloaded by id to read its full SAN list
+type CrtShSource struct {
+	// URL, ApexURL and CertURL are templates, {domain} and {id} are replaced. ApexURL is queried as well as
+	// URL when set, for the certificates a subdomain wildcard query misses
+	URL     string
+	ApexURL string
+	CertURL string
+	Client  *http.Client
+}
+
+func (s *CrtShSource) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *CrtShSource) query(ctx context.Context, tmpl, domain string) ([]CTEntry, error) {
+	body, err := s.get(ctx, strings.ReplaceAll(tmpl, "{domain}", url.QueryEscape(domain)))
+	if err != nil {
+		return nil, err
+	}
+	return decodeCrtSh(bytes.NewReader(body))
+}
+
+func (s *CrtShSource) Certificates(ctx context.Context, domain string) ([]CTEntry, error) {
+	entries, err := s.query(ctx, s.URL, domain)
+	if err != nil {
+		return nil, err
+	}
+	if s.ApexURL != "" {
+		apex, err := s.query(ctx, s.ApexURL, domain)
+		if err != nil {
+			return nil, err
+		}
+		entries = mergeCTEntries(append(entries, apex...))
+	}
+	if s.CertURL == "" {
+		return entries, nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].NotBefore.After(entries[j].NotBefore) })
+	for i := range entries {
+		if i >= MaxCTCertificates || ctx.Err() != nil {
+			break
+		}
+		der, err := s.get(ctx, strings.ReplaceAll(s.CertURL, "{id}", strconv.FormatInt(entries[i].ID, 10)))
+		if err != nil {
+			log.Println("Error fetching CT certificate: ", err)
+			continue
+		}
+		certs, err := parseCertificates(der)
+		if err != nil || len(certs) == 0 {
+			continue
+		}
+		entries[i].Names = append(entries[i].Names, certs[0].DNSNames...)
+	}
+	return entries, nil
+}
+
+// CTMirrorSource reads <Dir>/<domain>.json files in crt.sh JSON format from a local CT log mirror
+type CTMirrorSource struct {
+	Dir string
+}
+
+func (s *CTMirrorSource) Certificates(_ context.Context, domain string) ([]CTEntry, error) {
+	f, err := os.Open(filepath.Join(s.Dir, domain+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeCrtSh(f)
+}
+
+// CTBulkSource reads a bulk file of PEM blocks, or of base64 DER (pre)certificates one per line. The file is
+// parsed once per process
+type CTBulkSource struct {
+	Path string
+}
+
+var (
+	ctBulkEntries = make(map[string][]CTEntry)
+	ctBulkMut     sync.Mutex
+)
+
+// parseCertificates parses PEM blocks, base64 DER lines or raw DER
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		var certs []*x509.Certificate
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			// Precertificates carry a critical poison extension, which x509 records rather than rejects
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				certs = append(certs, cert)
+			}
+		}
+		return certs, nil
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}, nil
+	}
+	var certs []*x509.Certificate
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(scanner.Text()))
+		if err != nil || len(der) == 0 {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	return certs, scanner.Err()
+}
+
+func (s *CTBulkSource) load() ([]CTEntry, error) {
+	ctBulkMut.Lock()
+	defer ctBulkMut.Unlock()
+	if entries, ok := ctBulkEntries[s.Path]; ok {
+		return entries, nil
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	var entries []CTEntry
+	for _, cert := range certs {
+		entries = append(
+			entries, CTEntry{
+				Issuer: cert.Issuer.String(), Names: cert.DNSNames, NotBefore: cert.NotBefore, NotAfter: cert.NotAfter,
+			},
+		)
+	}
+	ctBulkEntries[s.Path] = entries
+	return entries, nil
+}
+
+func (s *CTBulkSource) Certificates(_ context.Context, domain string) ([]CTEntry, error) {
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var matched []CTEntry
+	for _, e := range entries {
+		for _, name := range e.Names {
+			name = strings.TrimPrefix(strings.ToLower(name), "*.")
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				matched = append(matched, e)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// NewCTSource returns the CT source for spec: an http(s) URL of a crt.sh-compatible endpoint, a local mirror
+// directory, or a bulk certificate file
+func NewCTSource(spec string) (CTSource, error) {
+	client, err := NewHTTPClient(HTTPConfig{})
+	if err != nil {
+		return nil, err
+	}
+	return newCTSource(spec, client)
+}
+
+// ctHTTPTimeout is the least time given to CT queries, crt.sh can take a while to answer wildcard queries
+const ctHTTPTimeout = 60 * time.Second
+
+func newCTSource(spec string, client *http.Client) (CTSource, error) {
+	if spec == "" {
+		spec = DefaultCTSource
+	}
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		if client.Timeout < ctHTTPTimeout {
+			c := *client
+			c.Timeout = ctHTTPTimeout
+			client = &c
+		}
+		src := &CrtShSource{URL: spec, Client: client}
+		if strings.Contains(spec, ctWildcard) {
+			src.ApexURL = strings.Replace(spec, ctWildcard, "{domain}", 1)
+		}
+		if u, err := url.Parse(spec); err == nil && u.Host == "crt.sh" {
+			src.CertURL = u.Scheme + "://" + u.Host + "/?d={id}"
+		}
+		return src, nil
+	}
+	info, err := os.Stat(spec)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &CTMirrorSource{Dir: spec}, nil
+	}
+	return &CTBulkSource{Path: spec}, nil
+}
+
+func (d *Domain) ctSource() (CTSource, error) {
+	client, err := d.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	if d.config != nil {
+		return newCTSource(d.config.CTSource, client)
+	}
+	return newCTSource("", client)
+}
+
+// GetCTLogDomainsContext links the domain to the registrable domains named alongside it on certificates logged to
+// Certificate Transparency, including certificates no longer served
+func (d *Domain) GetCTLogDomainsContext(ctx context.Context) error {
+	d.LastRanCT = time.Now()
+	if d.NonPublicDomain {
+		return ErrNonPublicDomain
+	}
+	src, err := d.ctSource()
+	if err != nil {
+		return err
+	}
+	entries, err := src.Certificates(ctx, d.DomainName)
+	if err != nil {
+		return err
+	}
+	domsFound := make(map[string]CTLogDomain)
+	for _, df := range d.CTLogDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	for _, e := range entries {
+		doms, hosts := sanDomains(e.Names, d.DomainName)
+		for _, dn := range doms {
+			df, exists := domsFound[dn]
+			if !exists {
+				df = CTLogDomain{
+					MatchedDomain: MatchedDomain{CreatedAt: now, DomainName: dn},
+					FirstSeen:     e.NotBefore,
+					LastSeen:      e.NotAfter,
+				}
+			}
+			df.UpdatedAt = now
+			if !e.NotBefore.IsZero() && (df.FirstSeen.IsZero() || e.NotBefore.Before(df.FirstSeen)) {
+				df.FirstSeen = e.NotBefore
+			}
+			if e.NotAfter.After(df.LastSeen) {
+				df.LastSeen = e.NotAfter
+			}
+			for _, h := range hosts[dn] {
+				df.AddHost(h)
+			}
+			domsFound[dn] = df
+		}
+	}
+	var cd []CTLogDomain
+	for _, df := range domsFound {
+		cd = append(cd, df)
+	}
+	d.CTLogDomains = cd
+	return nil
+}
@@ -0,0 +1,122 @@
+package domains
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const crtShJSON = `[
+	{"id": 2, "issuer_name": "C=US, O=Let's Encrypt, CN=R3", "common_name": "acme.com",
+	 "name_value": "acme.com\nshop.acme.com", "not_before": "2023-01-01T00:00:00", "not_after": "2023-04-01T00:00:00"},
+	{"id": 2, "issuer_name": "C=US, O=Let's Encrypt, CN=R3", "common_name": "acme.com",
+	 "name_value": "legacy.acme-old.com", "not_before": "2023-01-01T00:00:00", "not_after": "2023-04-01T00:00:00"},
+	{"id": 1, "issuer_name": "C=US, O=Let's Encrypt, CN=R3", "common_name": "acme.com",
+	 "name_value": "acme.com", "not_before": "2021-06-01T00:00:00", "not_after": "2021-09-01T00:00:00"}
+]`
+
+// crtShApexJSON is a certificate naming only the apex, which the subdomain wildcard query misses
+const crtShApexJSON = `[
+	{"id": 3, "issuer_name": "C=US, O=Let's Encrypt, CN=R3", "common_name": "acme.com",
+	 "name_value": "acme.com\nacme-apex.net", "not_before": "2024-01-01T00:00:00", "not_after": "2024-04-01T00:00:00"}
+]`
+
+func ctDomains(d *Domain) []string {
+	var doms []string
+	for _, c := range d.CTLogDomains {
+		doms = append(doms, c.DomainName)
+	}
+	sort.Strings(doms)
+	return doms
+}
+
+func TestGetCTLogDomainsCrtSh(t *testing.T) {
+	cert, _ := newTestCert(t, "Acme Corp", "acme.com", "acme-partner.net")
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Query().Get("q") == "%.acme.com":
+					w.Write([]byte(crtShJSON))
+				case r.URL.Query().Get("q") == "acme.com":
+					w.Write([]byte(crtShApexJSON))
+				case r.URL.Query().Get("d") == "1":
+					pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	t.Cleanup(srv.Close)
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{CTSource: srv.URL + "/?q=%25.{domain}&output=json"}
+	if err := d.GetCTLogDomains(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ctDomains(d); strings.Join(got, ",") != "acme-apex.net,acme-old.com" {
+		t.Errorf("CTLogDomains = %v, want [acme-apex.net acme-old.com]", got)
+	}
+	for _, c := range d.CTLogDomains {
+		if c.DomainName == "acme-old.com" && !c.FirstSeen.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("FirstSeen = %v", c.FirstSeen)
+		}
+	}
+
+	// Downloading each certificate recovers SANs crt.sh leaves out of name_value
+	src := &CrtShSource{URL: srv.URL + "/?q=%25.{domain}&output=json", CertURL: srv.URL + "/?d={id}", Client: srv.Client()}
+	entries, err := src.Certificates(context.Background(), "acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Names...)
+	}
+	if !strings.Contains(strings.Join(names, ","), "acme-partner.net") {
+		t.Errorf("names = %v, want acme-partner.net from the downloaded certificate", names)
+	}
+}
+
+func TestGetCTLogDomainsLocal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acme.com.json"), []byte(crtShJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := newTestCert(t, "Acme Corp", "www.acme.com", "acme-bulk.org")
+	other, _ := newTestCert(t, "Other", "other.com", "unrelated.org")
+	bulk := filepath.Join(t.TempDir(), "precerts.pem")
+	var data []byte
+	for _, c := range [][]byte{cert.Certificate[0], other.Certificate[0]} {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+	if err := os.WriteFile(bulk, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for source, want := range map[string]string{dir: "acme-old.com", bulk: "acme-bulk.org"} {
+		d, err := NewDomain("acme.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.config = &EnrichmentConfig{CTSource: source}
+		if err := d.GetCTLogDomains(); err != nil {
+			t.Fatal(err)
+		}
+		if got := ctDomains(d); fmt.Sprint(got) != "["+want+"]" {
+			t.Errorf("%s: CTLogDomains = %v, want [%s]", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type DmarcRecord struct {
+	CreatedAt       time.Time `json:"createdAt,omitempty"`
+	UpdatedAt       time.Time `json:"updatedAt,omitempty"`
+	Raw             string    `json:"raw,omitempty"`
+	Policy          string    `json:"p,omitempty"`
+	SubdomainPolicy string    `json:"sp,omitempty"`
+	Pct             int       `json:"pct,omitempty"`
+	Rua             []string  `json:"rua,omitempty"`
+	Ruf             []string  `json:"ruf,omitempty"`
+}
+
+type DKIMRecord struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	Selector  string    `json:"selector,omitempty"`
+	KeyType   string    `json:"keyType,omitempty"`
+	PublicKey string    `json:"publicKey,omitempty"`
+}
+
+type DmarcReportDomain struct {
+	MatchedDomain
+	// Mailbox is the rua or ruf address that points at the matched domain
+	Mailbox string `json:"mailbox,omitempty"`
+}
+
+var (
+	// DKIMSelectors are the selectors queried under _domainkey
+	DKIMSelectors = []string{
+		"default", "selector1", "selector2", "google", "k1", "k2", "k3", "s1", "s2", "dkim", "mail", "smtp",
+		"mandrill", "mxvault", "pm", "sig1", "everlytickey1", "everlytickey2", "zendesk1", "zendesk2", "hs1", "hs2",
+		"sendgrid", "mailjet", "amazonses", "protonmail", "fm1", "fm2", "fm3",
+	}
+	// DmarcVendors are registrable domains of DMARC reporting services, mailboxes at them are not ownership evidence
+	DmarcVendors = []string{
+		"dmarcian.com", "dmarcian.eu", "agari.com", "valimail.com", "proofpoint.com", "ondmarc.com", "redsift.cloud",
+		"mimecast.com", "easydmarc.com", "easydmarc.us", "easydmarc.eu", "dmarcadvisor.com", "uriports.com",
+		"powerdmarc.com", "postmarkapp.com", "cloudflare.net", "dmarcreport.com", "fraudmarc.com", "returnpath.net",
+		"250ok.net", "mailhardener.com", "glockapps.com", "sendmarc.com", "kdmarc.com", "dmarcly.com",
+		"emailauth.io", "dmarc.io", "dmarcanalyzer.com", "vali.email", "socketlabs.com", "google.com",
+		"microsoft.com", "barracudanetworks.com", "mailinblack.com", "inboxmonster.com", "dmarc-service.com",
+	}
+)
+
+func (d *Domain) dkimSelectors() []string {
+	if d.config != nil && len(d.config.DkimSelectors) > 0 {
+		return d.config.DkimSelectors
+	}
+	return DKIMSelectors
+}
+
+func (d *Domain) dmarcVendors() map[string]bool {
+	vendors := make(map[string]bool)
+	for _, v := range DmarcVendors {
+		vendors[v] = true
+	}
+	if d.config != nil {
+		for _, v := range d.config.DmarcVendors {
+			vendors[strings.ToLower(strings.TrimSpace(v))] = true
+		}
+	}
+	return vendors
+}
+
+// parseTagList parses a DMARC or DKIM tag=value; list
+func parseTagList(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		k, v, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return tags
+}
+
+// parseDmarcURIs returns the mailboxes of a rua or ruf value, dropping the mailto: scheme and size limits
+func parseDmarcURIs(v string) []string {
+	var mailboxes []string
+	for _, uri := range strings.Split(v, ",") {
+		uri = strings.TrimSpace(uri)
+		if !strings.HasPrefix(strings.ToLower(uri), "mailto:") {
+			continue
+		}
+		mailbox, _, _ := strings.Cut(uri[len("mailto:"):], "!")
+		if mailbox = strings.ToLower(strings.TrimSpace(mailbox)); mailbox != "" {
+			mailboxes = append(mailboxes, mailbox)
+		}
+	}
+	return mailboxes
+}
+
+func (d *Domain) lookupTXT(ctx context.Context, host string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(host), dns.TypeTXT)
+	r, err := d.queryAllServers(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	var txts []string
+	for _, ans := range r.Answer {
+		if a, ok := ans.(*dns.TXT); ok {
+			txts = append(txts, strings.Join(a.Txt, ""))
+		}
+	}
+	return txts, nil
+}
+
+func (d *Domain) QueryDmarcContext(ctx context.Context) error {
+	txts, err := d.lookupTXT(ctx, "_dmarc."+d.DomainName)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, txt := range txts {
+		tags := parseTagList(txt)
+		if !strings.EqualFold(tags["v"], "DMARC1") {
+			continue
+		}
+		rec := &DmarcRecord{
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			Raw:             txt,
+			Policy:          strings.ToLower(tags["p"]),
+			SubdomainPolicy: strings.ToLower(tags["sp"]),
+			Pct:             100,
+			Rua:             parseDmarcURIs(tags["rua"]),
+			Ruf:             parseDmarcURIs(tags["ruf"]),
+		}
+		if pct, err := strconv.Atoi(tags["pct"]); err == nil {
+			rec.Pct = pct
+		}
+		if d.Dmarc != nil && !d.Dmarc.CreatedAt.IsZero() && d.Dmarc.Raw == rec.Raw {
+			rec.CreatedAt = d.Dmarc.CreatedAt
+		}
+		d.Dmarc = rec
+		return nil
+	}
+	d.Dmarc = nil
+	return nil
+}
+
+// QueryDKIMContext looks up each DKIM selector, keeping the selectors that publish a key
+func (d *Domain) QueryDKIMContext(ctx context.Context) error {
+	foundDKIM := make(map[string]DKIMRecord)
+	for _, k := range d.DKIMRecords {
+		foundDKIM[k.Selector] = k
+	}
+	now := time.Now()
+	var errs []string
+	for _, selector := range d.dkimSelectors() {
+		txts, err := d.lookupTXT(ctx, selector+"._domainkey."+d.DomainName)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, txt := range txts {
+			tags := parseTagList(txt)
+			key, hasKey := tags["p"]
+			if !hasKey {
+				continue
+			}
+			keyType := strings.ToLower(tags["k"])
+			if keyType == "" {
+				keyType = "rsa"
+			}
+			if k, ok := foundDKIM[selector]; ok {
+				k.UpdatedAt = now
+				k.KeyType = keyType
+				k.PublicKey = key
+				foundDKIM[selector] = k
+			} else {
+				foundDKIM[selector] = DKIMRecord{
+					CreatedAt: now, UpdatedAt: now, Selector: selector, KeyType: keyType, PublicKey: key,
+				}
+			}
+			break
+		}
+	}
+	var dkims []DKIMRecord
+	for _, k := range foundDKIM {
+		dkims = append(dkims, k)
+	}
+	d.DKIMRecords = dkims
+	if len(errs) > 0 {
+		return fmt.Errorf("Error querying DKIM selectors: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// GetDmarcReportDomainsContext queries DMARC and DKIM, then links the domain to third party domains receiving its
+// DMARC reports that are not known reporting vendors
+func (d *Domain) GetDmarcReportDomainsContext(ctx context.Context) error {
+	d.LastRanDmarc = time.Now()
+	if d.NonPublicDomain {
+		return ErrNonPublicDomain
+	}
+	if err := d.QueryDmarcContext(ctx); err != nil {
+		return err
+	}
+	dkimErr := d.QueryDKIMContext(ctx)
+	vendors := d.dmarcVendors()
+	domsFound := make(map[string]DmarcReportDomain)
+	for _, df := range d.DmarcReportDomains {
+		domsFound[df.DomainName] = df
+	}
+	seen := make(map[string]bool)
+	now := time.Now()
+	if d.Dmarc != nil {
+		for _, mailbox := range append(append([]string{}, d.Dmarc.Rua...), d.Dmarc.Ruf...) {
+			_, host, found := strings.Cut(mailbox, "@")
+			if !found {
+				continue
+			}
+			dom, err := NewDomain(host)
+			if err != nil {
+				log.Println("Error parsing domain: ", err)
+				continue
+			}
+			if dom.DomainName == d.DomainName || vendors[dom.DomainName] || seen[dom.DomainName] {
+				continue
+			}
+			seen[dom.DomainName] = true
+			df, exists := domsFound[dom.DomainName]
+			if !exists {
+				df = DmarcReportDomain{MatchedDomain: MatchedDomain{CreatedAt: now, DomainName: dom.DomainName}}
+			}
+			df.UpdatedAt = now
+			df.Mailbox = mailbox
+			df.AddHost(dom.FQDN())
+			domsFound[dom.DomainName] = df
+		}
+	}
+	var dd []DmarcReportDomain
+	for _, df := range domsFound {
+		dd = append(dd, df)
+	}
+	d.DmarcReportDomains = dd
+	return dkimErr
+}
@@ -0,0 +1,43 @@
+package domains
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetDmarcReportDomains(t *testing.T) {
+	addr := newDNSServer(
+		t, answerTXT(
+			map[string]string{
+				"_dmarc.acme.com.": "v=DMARC1; p=reject; sp=quarantine; pct=50; " +
+					"rua=mailto:dmarc@acme.com,mailto:reports@acme-group.com!10m,mailto:abc@ag.dmarcian.com; " +
+					"ruf=mailto:forensics@acme-group.com",
+				"selector1._domainkey.acme.com.": "v=DKIM1; k=rsa; p=MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQ",
+				"google._domainkey.acme.com.":    "v=DKIM1; p=",
+			},
+		),
+	)
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{
+		Resolvers: []string{addr}, DNSTimeout: time.Second, DkimSelectors: []string{"selector1", "google", "s1"},
+	}
+	if err := d.GetDmarcReportDomains(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Dmarc == nil || d.Dmarc.Policy != "reject" || d.Dmarc.SubdomainPolicy != "quarantine" || d.Dmarc.Pct != 50 {
+		t.Fatalf("Dmarc = %+v", d.Dmarc)
+	}
+	if len(d.Dmarc.Rua) != 3 || d.Dmarc.Rua[1] != "reports@acme-group.com" || len(d.Dmarc.Ruf) != 1 {
+		t.Errorf("rua = %v, ruf = %v", d.Dmarc.Rua, d.Dmarc.Ruf)
+	}
+	if len(d.DKIMRecords) != 2 {
+		t.Errorf("DKIMRecords = %v", d.DKIMRecords)
+	}
+	matched := d.GetAllMatchedDomains().DmarcReportDomains
+	if len(matched) != 1 || matched[0] != "acme-group.com" {
+		t.Errorf("DmarcReportDomains = %v", matched)
+	}
+}
@@ -1,8 +1,14 @@
 package domains
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/miekg/dns"
@@ -12,12 +18,14 @@ type AAAARecord struct {
 	CreatedAt time.Time `json:"createdAt,omitempty"`
 	UpdatedAt time.Time `json:"updatedAt,omitempty"`
 	IPV6      string    `json:"ip_v6"`
+	PTR       []string  `json:"ptr,omitempty"`
 }
 
 type ARecord struct {
 	CreatedAt time.Time `json:"createdAt,omitempty"`
 	UpdatedAt time.Time `json:"updatedAt,omitempty"`
 	IP        string    `json:"ip"`
+	PTR       []string  `json:"ptr,omitempty"`
 }
 
 type SOARecord struct {
@@ -35,14 +43,15 @@ type MXRecord struct {
 }
 
 var (
-	DomainClient *dns.Client
-	ClientConfig *dns.ClientConfig
+	DomainClient    *dns.Client
+	ClientConfig    *dns.ClientConfig
+	DefaultResolver *Resolver
 )
 
-func (d *Domain) QueryMX() error {
+func (d *Domain) QueryMXContext(ctx context.Context) error {
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeMX)
-	r, err := queryAllServers(msg)
+	r, err := d.queryAllServers(ctx, msg)
 	if err != nil {
 		return err
 	}
@@ -74,10 +83,10 @@ func (d *Domain) QueryMX() error {
 	return nil
 }
 
-func (d *Domain) QueryA() error {
+func (d *Domain) QueryAContext(ctx context.Context) error {
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeA)
-	r, err := queryAllServers(msg)
+	r, err := d.queryAllServers(ctx, msg)
 	if err != nil {
 		return err
 	}
@@ -102,13 +111,17 @@ func (d *Domain) QueryA() error {
 		ips = append(ips, ip)
 	}
 	d.ARecords = ips
+	// Only the A query reports a missing domain, the other record types would repeat it
+	if r.Rcode == dns.RcodeNameError {
+		return fmt.Errorf("%s: %w", d.DomainName, ErrNXDomain)
+	}
 	return nil
 }
 
-func (d *Domain) QueryAAAA() error {
+func (d *Domain) QueryAAAAContext(ctx context.Context) error {
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeAAAA)
-	r, err := queryAllServers(msg)
+	r, err := d.queryAllServers(ctx, msg)
 	if err != nil {
 		return err
 	}
@@ -136,10 +149,10 @@ func (d *Domain) QueryAAAA() error {
 	return nil
 }
 
-func (d *Domain) QuerySOA() error {
+func (d *Domain) QuerySOAContext(ctx context.Context) error {
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeSOA)
-	r, err := queryAllServers(msg)
+	r, err := d.queryAllServers(ctx, msg)
 	if err != nil {
 		return err
 	}
@@ -154,49 +167,146 @@ func (d *Domain) QuerySOA() error {
 	return nil
 }
 
-func query(msg *dns.Msg, nameserver string) (*dns.Msg, error) {
-	r, _, err := DomainClient.Exchange(msg, nameserver)
-	return r, err
+// Resolver queries its servers in order over its transport, failing over to the next server on error
+type Resolver struct {
+	Servers   []string
+	Transport DNSTransport
+}
+
+var resolvers sync.Map
+
+func NewResolver(servers []string, transport DNSTransport) *Resolver {
+	var addrs []string
+	for _, s := range servers {
+		if s = strings.TrimSpace(s); s != "" {
+			addrs = append(addrs, transport.Address(s))
+		}
+	}
+	return &Resolver{Servers: addrs, Transport: transport}
+}
+
+// getResolver returns a shared resolver for the given transport, servers and timeout
+func getResolver(transport string, servers []string, timeout time.Duration) (*Resolver, error) {
+	key := fmt.Sprintf("%s|%s|%s", transport, strings.Join(servers, ","), timeout)
+	if r, ok := resolvers.Load(key); ok {
+		return r.(*Resolver), nil
+	}
+	t, err := NewDNSTransport(transport, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		servers = t.DefaultServers()
+	}
+	r, _ := resolvers.LoadOrStore(key, NewResolver(servers, t))
+	return r.(*Resolver), nil
+}
+
+// Exchange sends msg to each server in turn until one answers
+func (r *Resolver) Exchange(msg *dns.Msg) (*dns.Msg, error) {
+	return r.ExchangeContext(context.Background(), msg)
+}
+
+// ExchangeContext is Exchange, giving up on the remaining servers once ctx is done
+func (r *Resolver) ExchangeContext(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	var failed []string
+	for _, server := range r.Servers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := r.Transport.Exchange(ctx, msg, server)
+		if err == nil && (resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused) {
+			err = fmt.Errorf("received %s", dns.RcodeToString[resp.Rcode])
+		}
+		if err == nil {
+			return resp, nil
+		}
+		failed = append(failed, fmt.Sprintf("%s (%v)", server, err))
+	}
+	if len(failed) == 0 {
+		return nil, errors.New("No DNS servers configured")
+	}
+	return nil, fmt.Errorf("Failed to query all servers: %s", strings.Join(failed, ", "))
 }
 
-func queryAllServers(msg *dns.Msg) (*dns.Msg, error) {
-	r, err := query(msg, "8.8.8.8:53")
-	if err == nil {
-		return r, nil
+func (d *Domain) resolver() *Resolver {
+	if d.config == nil || (len(d.config.Resolvers) == 0 && d.config.DNSTimeout == 0 && d.config.DNSTransport == "") {
+		return DefaultResolver
 	}
-	return nil, errors.New("Failed to query all servers")
+	r, err := getResolver(d.config.DNSTransport, d.config.Resolvers, d.config.DNSTimeout)
+	if err != nil {
+		log.Printf("Error creating resolver, using the default: %s\n", err)
+		return DefaultResolver
+	}
+	return r
+}
+
+func (d *Domain) queryAllServers(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	return d.resolver().ExchangeContext(ctx, msg)
 }
 
-func (d *Domain) GetDNSRecords() []error {
+func (d *Domain) GetDNSRecordsContext(ctx context.Context) []error {
 	d.LastRanDns = time.Now()
 	if d.NonPublicDomain {
-		return []error{errors.New("Non public domain")}
+		return []error{ErrNonPublicDomain}
 	}
 	errs := []error{}
-	err := d.QueryA()
+	err := d.QueryAContext(ctx)
 	if err != nil {
 		errs = append(errs, err)
 	}
-	err = d.QueryAAAA()
+	err = d.QueryAAAAContext(ctx)
 	if err != nil {
 		errs = append(errs, err)
 	}
-	err = d.QueryMX()
+	err = d.QueryPTRContext(ctx)
 	if err != nil {
 		errs = append(errs, err)
 	}
-	err = d.QuerySOA()
+	err = d.QueryMXContext(ctx)
 	if err != nil {
 		errs = append(errs, err)
 	}
+	err = d.QuerySOAContext(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, query := range []func(context.Context) error{
+		d.QueryNSContext, d.QueryTXTContext, d.QueryCNAMEContext, d.QueryCAAContext, d.QuerySRVContext,
+	} {
+		if err := query(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	return errs
 }
 
-func init() {
+// defaultServers returns the resolvers named in DOMWALK_RESOLVERS, else those of the resolv.conf
+// file named in DOMWALK_RESOLV_CONF or /etc/resolv.conf
+func defaultServers() ([]string, time.Duration) {
+	if env := os.Getenv("DOMWALK_RESOLVERS"); env != "" {
+		return strings.Split(env, ","), defaultDNSTimeout
+	}
+	path := os.Getenv("DOMWALK_RESOLV_CONF")
+	if path == "" {
+		path = "/etc/resolv.conf"
+	}
 	var err error
-	ClientConfig, err = dns.ClientConfigFromFile("/etc/resolv.conf") // TODO: Make this part of the config
-	if err != nil {
-		log.Fatal(err)
+	ClientConfig, err = dns.ClientConfigFromFile(path)
+	if err != nil || len(ClientConfig.Servers) == 0 {
+		log.Printf("Unable to read nameservers from %s, falling back to 8.8.8.8: %v", path, err)
+		return []string{"8.8.8.8:53"}, defaultDNSTimeout
 	}
-	DomainClient = new(dns.Client)
+	var servers []string
+	for _, s := range ClientConfig.Servers {
+		servers = append(servers, net.JoinHostPort(s, ClientConfig.Port))
+	}
+	return servers, time.Duration(ClientConfig.Timeout) * time.Second
+}
+
+func init() {
+	servers, timeout := defaultServers()
+	transport := NewClassicTransport(timeout)
+	DefaultResolver = NewResolver(servers, transport)
+	DomainClient = transport.UDP
 }
@@ -0,0 +1,252 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type NSRecord struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	NS        string    `json:"ns,omitempty"`
+}
+
+type TXTRecord struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	Text      string    `json:"text,omitempty"`
+}
+
+type CNAMERecord struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Target    string    `json:"target,omitempty"`
+}
+
+type CAARecord struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	Flag      uint8     `json:"flag,omitempty"`
+	Tag       string    `json:"tag,omitempty"`
+	Value     string    `json:"value,omitempty"`
+}
+
+type SRVRecord struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	Service   string    `json:"service,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	Port      uint16    `json:"port,omitempty"`
+	Priority  uint16    `json:"priority,omitempty"`
+	Weight    uint16    `json:"weight,omitempty"`
+}
+
+var (
+	// SRVServices are the service labels queried under the domain for SRV records
+	SRVServices = []string{
+		"_sip._tcp", "_sip._udp", "_sip._tls", "_sipfederationtls._tcp", "_autodiscover._tcp", "_xmpp-server._tcp",
+		"_xmpp-client._tcp", "_caldavs._tcp", "_carddavs._tcp", "_imaps._tcp", "_submission._tcp",
+	}
+	// CNAMEHosts are the labels, relative to the domain, whose CNAME chains are followed. "" is the apex
+	CNAMEHosts = []string{"", "www"}
+)
+
+const maxCNAMEChain = 10
+
+func (d *Domain) QueryNSContext(ctx context.Context) error {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeNS)
+	r, err := d.queryAllServers(ctx, msg)
+	if err != nil {
+		return err
+	}
+	foundNS := make(map[string]NSRecord)
+	for _, n := range d.NSRecords {
+		foundNS[n.NS] = n
+	}
+	now := time.Now()
+	for _, ans := range r.Answer {
+		if a, ok := ans.(*dns.NS); ok {
+			ns := strings.ToLower(a.Ns)
+			if n, ok := foundNS[ns]; ok {
+				n.UpdatedAt = now
+				foundNS[ns] = n
+				continue
+			}
+			foundNS[ns] = NSRecord{CreatedAt: now, UpdatedAt: now, NS: ns}
+		}
+	}
+	var nss []NSRecord
+	for _, n := range foundNS {
+		nss = append(nss, n)
+	}
+	d.NSRecords = nss
+	return nil
+}
+
+func (d *Domain) QueryTXTContext(ctx context.Context) error {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeTXT)
+	r, err := d.queryAllServers(ctx, msg)
+	if err != nil {
+		return err
+	}
+	foundTXT := make(map[string]TXTRecord)
+	for _, t := range d.TXTRecords {
+		foundTXT[t.Text] = t
+	}
+	now := time.Now()
+	for _, ans := range r.Answer {
+		if a, ok := ans.(*dns.TXT); ok {
+			// Long records are split into several strings which form one value
+			txt := strings.Join(a.Txt, "")
+			if t, ok := foundTXT[txt]; ok {
+				t.UpdatedAt = now
+				foundTXT[txt] = t
+				continue
+			}
+			foundTXT[txt] = TXTRecord{CreatedAt: now, UpdatedAt: now, Text: txt}
+		}
+	}
+	var txts []TXTRecord
+	for _, t := range foundTXT {
+		txts = append(txts, t)
+	}
+	d.TXTRecords = txts
+	return nil
+}
+
+// QueryCNAMEContext follows the CNAME chain of the apex and each of CNAMEHosts, recording every hop
+func (d *Domain) QueryCNAMEContext(ctx context.Context) error {
+	foundCNAME := make(map[string]CNAMERecord)
+	for _, c := range d.CNAMERecords {
+		foundCNAME[c.Host+"|"+c.Target] = c
+	}
+	now := time.Now()
+	var errs []string
+	for _, label := range CNAMEHosts {
+		host := dns.Fqdn(d.DomainName)
+		if label != "" {
+			host = dns.Fqdn(label + "." + d.DomainName)
+		}
+		seen := make(map[string]bool)
+		for i := 0; i < maxCNAMEChain && !seen[host]; i++ {
+			seen[host] = true
+			msg := new(dns.Msg)
+			msg.SetQuestion(host, dns.TypeCNAME)
+			r, err := d.queryAllServers(ctx, msg)
+			if err != nil {
+				errs = append(errs, err.Error())
+				break
+			}
+			var target string
+			for _, ans := range r.Answer {
+				if a, ok := ans.(*dns.CNAME); ok && strings.EqualFold(a.Hdr.Name, host) {
+					target = strings.ToLower(a.Target)
+					break
+				}
+			}
+			if target == "" {
+				break
+			}
+			key := host + "|" + target
+			if c, ok := foundCNAME[key]; ok {
+				c.UpdatedAt = now
+				foundCNAME[key] = c
+			} else {
+				foundCNAME[key] = CNAMERecord{CreatedAt: now, UpdatedAt: now, Host: host, Target: target}
+			}
+			host = target
+		}
+	}
+	var cnames []CNAMERecord
+	for _, c := range foundCNAME {
+		cnames = append(cnames, c)
+	}
+	d.CNAMERecords = cnames
+	if len(errs) > 0 {
+		return fmt.Errorf("Error querying CNAME records: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (d *Domain) QueryCAAContext(ctx context.Context) error {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(d.DomainName), dns.TypeCAA)
+	r, err := d.queryAllServers(ctx, msg)
+	if err != nil {
+		return err
+	}
+	foundCAA := make(map[string]CAARecord)
+	for _, c := range d.CAARecords {
+		foundCAA[fmt.Sprintf("%d|%s|%s", c.Flag, c.Tag, c.Value)] = c
+	}
+	now := time.Now()
+	for _, ans := range r.Answer {
+		if a, ok := ans.(*dns.CAA); ok {
+			key := fmt.Sprintf("%d|%s|%s", a.Flag, a.Tag, a.Value)
+			if c, ok := foundCAA[key]; ok {
+				c.UpdatedAt = now
+				foundCAA[key] = c
+				continue
+			}
+			foundCAA[key] = CAARecord{CreatedAt: now, UpdatedAt: now, Flag: a.Flag, Tag: a.Tag, Value: a.Value}
+		}
+	}
+	var caas []CAARecord
+	for _, c := range foundCAA {
+		caas = append(caas, c)
+	}
+	d.CAARecords = caas
+	return nil
+}
+
+// QuerySRVContext queries each of SRVServices under the domain
+func (d *Domain) QuerySRVContext(ctx context.Context) error {
+	foundSRV := make(map[string]SRVRecord)
+	for _, s := range d.SRVRecords {
+		foundSRV[fmt.Sprintf("%s|%s|%d", s.Service, s.Target, s.Port)] = s
+	}
+	now := time.Now()
+	var errs []string
+	for _, service := range SRVServices {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(service+"."+d.DomainName), dns.TypeSRV)
+		r, err := d.queryAllServers(ctx, msg)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, ans := range r.Answer {
+			if a, ok := ans.(*dns.SRV); ok {
+				target := strings.ToLower(a.Target)
+				key := fmt.Sprintf("%s|%s|%d", service, target, a.Port)
+				if s, ok := foundSRV[key]; ok {
+					s.UpdatedAt = now
+					s.Priority = a.Priority
+					s.Weight = a.Weight
+					foundSRV[key] = s
+					continue
+				}
+				foundSRV[key] = SRVRecord{
+					CreatedAt: now, UpdatedAt: now, Service: service, Target: target, Port: a.Port,
+					Priority: a.Priority, Weight: a.Weight,
+				}
+			}
+		}
+	}
+	var srvs []SRVRecord
+	for _, s := range foundSRV {
+		srvs = append(srvs, s)
+	}
+	d.SRVRecords = srvs
+	if len(errs) > 0 {
+		return fmt.Errorf("Error querying SRV records: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
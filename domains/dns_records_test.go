@@ -0,0 +1,163 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// newZoneServer answers from records in zone file format. Names without records are NXDOMAIN
+func newZoneServer(t *testing.T, records ...string) string {
+	zone := make(map[string][]dns.RR)
+	for _, s := range records {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.ToLower(rr.Header().Name)
+		zone[name] = append(zone[name], rr)
+	}
+	return newDNSServer(
+		t, dns.HandlerFunc(
+			func(w dns.ResponseWriter, r *dns.Msg) {
+				m := new(dns.Msg)
+				m.SetReply(r)
+				q := r.Question[0]
+				rrs, ok := zone[strings.ToLower(q.Name)]
+				if !ok {
+					m.Rcode = dns.RcodeNameError
+				}
+				for _, rr := range rrs {
+					if rr.Header().Rrtype == q.Qtype {
+						m.Answer = append(m.Answer, rr)
+					}
+				}
+				w.WriteMsg(m)
+			},
+		),
+	)
+}
+
+func TestQueryRecords(t *testing.T) {
+	records := []string{
+		"example.com. 300 IN NS ns1.example.com.",
+		"example.com. 300 IN NS NS2.Example.net.",
+		`example.com. 300 IN TXT "v=spf1 include:_spf.example.net " "~all"`,
+		`example.com. 300 IN TXT "site-verification=abc"`,
+		`example.com. 300 IN CAA 0 issue "letsencrypt.org"`,
+		`example.com. 300 IN CAA 128 iodef "mailto:security@example.com"`,
+		"www.example.com. 300 IN CNAME edge.CDN.example.net.",
+		"edge.cdn.example.net. 300 IN CNAME origin.example.net.",
+		"origin.example.net. 300 IN A 192.0.2.1",
+		"_sip._tls.example.com. 300 IN SRV 100 1 443 sipdir.online.lync.com.",
+		"_autodiscover._tcp.example.com. 300 IN SRV 0 0 443 Mail.Example.com.",
+		// www.loop.com and a.loop.com point at each other
+		"www.loop.com. 300 IN CNAME a.loop.com.",
+		"a.loop.com. 300 IN CNAME www.loop.com.",
+	}
+	// www.long.com starts a chain of 15 hops, only the first 10 are followed
+	records = append(records, "www.long.com. 300 IN CNAME h1.long.com.")
+	for i := 1; i < 15; i++ {
+		records = append(records, fmt.Sprintf("h%d.long.com. 300 IN CNAME h%d.long.com.", i, i+1))
+	}
+	addr := newZoneServer(t, records...)
+
+	sorted := func(ss []string) []string {
+		sort.Strings(ss)
+		return ss
+	}
+	cnameCount := func(d *Domain) any { return len(d.CNAMERecords) }
+	tests := []struct {
+		name   string
+		domain string
+		query  func(*Domain, context.Context) error
+		got    func(*Domain) any
+		want   any
+	}{
+		{
+			"ns", "example.com", (*Domain).QueryNSContext,
+			func(d *Domain) any {
+				var ss []string
+				for _, n := range d.NSRecords {
+					ss = append(ss, n.NS)
+				}
+				return sorted(ss)
+			},
+			[]string{"ns1.example.com.", "ns2.example.net."},
+		},
+		{
+			"txt", "example.com", (*Domain).QueryTXTContext,
+			func(d *Domain) any {
+				var ss []string
+				for _, r := range d.TXTRecords {
+					ss = append(ss, r.Text)
+				}
+				return sorted(ss)
+			},
+			[]string{"site-verification=abc", "v=spf1 include:_spf.example.net ~all"},
+		},
+		{
+			"cname", "example.com", (*Domain).QueryCNAMEContext,
+			func(d *Domain) any {
+				var ss []string
+				for _, c := range d.CNAMERecords {
+					ss = append(ss, c.Host+" "+c.Target)
+				}
+				return sorted(ss)
+			},
+			[]string{"edge.cdn.example.net. origin.example.net.", "www.example.com. edge.cdn.example.net."},
+		},
+		{"cname loop", "loop.com", (*Domain).QueryCNAMEContext, cnameCount, 2},
+		{"cname chain limit", "long.com", (*Domain).QueryCNAMEContext, cnameCount, maxCNAMEChain},
+		{
+			"caa", "example.com", (*Domain).QueryCAAContext,
+			func(d *Domain) any {
+				var ss []string
+				for _, c := range d.CAARecords {
+					ss = append(ss, fmt.Sprintf("%d %s %s", c.Flag, c.Tag, c.Value))
+				}
+				return sorted(ss)
+			},
+			[]string{"0 issue letsencrypt.org", "128 iodef mailto:security@example.com"},
+		},
+		{
+			// Services answering NXDOMAIN are not errors
+			"srv", "example.com", (*Domain).QuerySRVContext,
+			func(d *Domain) any {
+				var ss []string
+				for _, s := range d.SRVRecords {
+					ss = append(ss, fmt.Sprintf("%s %s %d", s.Service, s.Target, s.Port))
+				}
+				return sorted(ss)
+			},
+			[]string{"_autodiscover._tcp mail.example.com. 443", "_sip._tls sipdir.online.lync.com. 443"},
+		},
+		{
+			"srv nxdomain", "loop.com", (*Domain).QuerySRVContext,
+			func(d *Domain) any { return len(d.SRVRecords) }, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				d, err := NewDomain(tt.domain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.config = &EnrichmentConfig{Resolvers: []string{addr}, DNSTimeout: time.Second}
+				if err := tt.query(d, context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				if got := tt.got(d); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
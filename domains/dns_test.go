@@ -0,0 +1,243 @@
+package domains
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// replyA answers A queries with 192.0.2.1
+func replyA(r *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	if r.Question[0].Qtype == dns.TypeA {
+		rr, _ := dns.NewRR(r.Question[0].Name + " 300 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, rr)
+	}
+	return m
+}
+
+// answerA serves replyA, udpTruncated forces a TCP retry
+func answerA(udpTruncated bool) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		if _, udp := w.RemoteAddr().(*net.UDPAddr); udp && udpTruncated {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Truncated = true
+			w.WriteMsg(m)
+			return
+		}
+		w.WriteMsg(replyA(r))
+	}
+}
+
+// newTestCert creates a self-signed certificate for the given hosts and a pool trusting it
+func newTestCert(t *testing.T, org string, hosts ...string) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: hosts[0], Organization: []string{org}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// newDNSServer serves handler over UDP and TCP on the same local port
+func newDNSServer(t *testing.T, handler dns.Handler) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp := &dns.Server{PacketConn: pc, Handler: handler}
+	tcp := &dns.Server{Listener: l, Handler: handler}
+	go udp.ActivateAndServe()
+	go tcp.ActivateAndServe()
+	t.Cleanup(
+		func() {
+			udp.Shutdown()
+			tcp.Shutdown()
+		},
+	)
+	return pc.LocalAddr().String()
+}
+
+func TestResolverFailover(t *testing.T) {
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.LocalAddr().String()
+	dead.Close()
+	live := newDNSServer(t, answerA(false))
+
+	d, err := NewDomain("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{Resolvers: []string{deadAddr, live}, DNSTimeout: 500 * time.Millisecond}
+	if err := d.QueryA(); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.ARecords) != 1 || d.ARecords[0].IP != "192.0.2.1" {
+		t.Errorf("ARecords = %v", d.ARecords)
+	}
+
+	r := NewResolver([]string{deadAddr, "127.0.0.1:1"}, NewClassicTransport(500*time.Millisecond))
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	_, err = r.Exchange(msg)
+	if err == nil {
+		t.Fatal("expected an error when every server fails")
+	}
+	for _, s := range []string{deadAddr, "127.0.0.1:1"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not name server %s", err, s)
+		}
+	}
+}
+
+func TestResolverTCPFallback(t *testing.T) {
+	addr := newDNSServer(t, answerA(true))
+	r := NewResolver([]string{addr}, NewClassicTransport(time.Second))
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	resp, err := r.Exchange(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Truncated || len(resp.Answer) != 1 {
+		t.Errorf("expected the full answer over TCP, got %v", resp)
+	}
+}
+
+func TestResolverTransportDefaults(t *testing.T) {
+	for transport, want := range map[string][]string{
+		DNSTransportUDP: DefaultResolver.Servers,
+		DNSTransportTCP: DefaultResolver.Servers,
+		DNSTransportDoT: (&DoTTransport{}).DefaultServers(),
+	} {
+		d := &Domain{config: &EnrichmentConfig{DNSTransport: transport}}
+		if got := d.resolver().Servers; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s resolver servers = %v, want %v", transport, got, want)
+		}
+	}
+}
+
+func TestDoTTransport(t *testing.T) {
+	cert, pool := newTestCert(t, "DoT Test", "127.0.0.1")
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{Listener: l, Net: "tcp-tls", Handler: answerA(false)}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	r := NewResolver([]string{l.Addr().String()}, NewDoTTransport(time.Second, &tls.Config{RootCAs: pool}))
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeA)
+	resp, err := r.Exchange(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("Answer = %v", resp.Answer)
+	}
+
+	untrusted := NewResolver([]string{l.Addr().String()}, NewDoTTransport(time.Second, nil))
+	if _, err := untrusted.Exchange(msg); err == nil {
+		t.Error("expected an error for an untrusted DoT certificate")
+	}
+}
+
+func TestDoHTransport(t *testing.T) {
+	srv := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var packed []byte
+				var err error
+				switch r.Method {
+				case http.MethodGet:
+					packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+				case http.MethodPost:
+					if r.Header.Get("Content-Type") != dnsMessageContentType {
+						http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+						return
+					}
+					packed, err = io.ReadAll(r.Body)
+				}
+				q := new(dns.Msg)
+				if err != nil || q.Unpack(packed) != nil {
+					http.Error(w, "bad request", http.StatusBadRequest)
+					return
+				}
+				if q.Id != 0 || r.URL.Query().Get("key") != "abc" {
+					http.Error(w, "id should be 0 and the key kept", http.StatusBadRequest)
+					return
+				}
+				out, _ := replyA(q).Pack()
+				w.Header().Set("Content-Type", dnsMessageContentType)
+				w.Write(out)
+			},
+		),
+	)
+	t.Cleanup(srv.Close)
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		transport := &DoHTransport{Client: srv.Client(), Method: method}
+		r := NewResolver([]string{srv.URL + "/dns-query?key=abc"}, transport)
+		msg := new(dns.Msg)
+		msg.SetQuestion("example.com.", dns.TypeA)
+		resp, err := r.Exchange(msg)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if resp.Id != msg.Id || len(resp.Answer) != 1 {
+			t.Errorf("%s: unexpected response %v", method, resp)
+		}
+	}
+}
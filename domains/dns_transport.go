@@ -0,0 +1,217 @@
+package domains
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	DNSTransportUDP    = "udp"
+	DNSTransportTCP    = "tcp"
+	DNSTransportDoT    = "dot"
+	DNSTransportDoH    = "doh"
+	DNSTransportDoHGet = "doh-get"
+
+	defaultDNSTimeout = 3 * time.Second
+)
+
+// DNSTransport exchanges a DNS message with a single server
+type DNSTransport interface {
+	// Address normalizes a configured server into the form Exchange expects
+	Address(server string) string
+	// DefaultServers are used when no resolvers are configured for the transport
+	DefaultServers() []string
+	Exchange(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, error)
+}
+
+func NewDNSTransport(name string, timeout time.Duration) (DNSTransport, error) {
+	switch name {
+	case "", DNSTransportUDP:
+		return NewClassicTransport(timeout), nil
+	case DNSTransportTCP:
+		t := NewClassicTransport(timeout)
+		t.UDP = nil
+		return t, nil
+	case DNSTransportDoT:
+		return NewDoTTransport(timeout, nil), nil
+	case DNSTransportDoH:
+		return NewDoHTransport(timeout, http.MethodPost), nil
+	case DNSTransportDoHGet:
+		return NewDoHTransport(timeout, http.MethodGet), nil
+	}
+	return nil, fmt.Errorf("Unknown DNS transport %q", name)
+}
+
+func withPort(server, port string) string {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return net.JoinHostPort(strings.Trim(server, "[]"), port)
+	}
+	return server
+}
+
+// ClassicTransport queries over UDP, retrying over TCP when the answer is truncated. Without a UDP
+// client every query goes over TCP
+type ClassicTransport struct {
+	UDP *dns.Client
+	TCP *dns.Client
+}
+
+func NewClassicTransport(timeout time.Duration) *ClassicTransport {
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	return &ClassicTransport{
+		UDP: &dns.Client{Net: "udp", Timeout: timeout},
+		TCP: &dns.Client{Net: "tcp", Timeout: timeout},
+	}
+}
+
+func (t *ClassicTransport) Address(server string) string {
+	return withPort(server, "53")
+}
+
+// DefaultServers are the system resolvers, whether queries go over UDP or TCP
+func (t *ClassicTransport) DefaultServers() []string {
+	return DefaultResolver.Servers
+}
+
+func (t *ClassicTransport) Exchange(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, error) {
+	if t.UDP != nil {
+		resp, _, err := t.UDP.ExchangeContext(ctx, msg, address)
+		if err != nil || !resp.Truncated {
+			return resp, err
+		}
+	}
+	resp, _, err := t.TCP.ExchangeContext(ctx, msg, address)
+	return resp, err
+}
+
+// DoTTransport queries over DNS-over-TLS (RFC 7858)
+type DoTTransport struct {
+	Client *dns.Client
+}
+
+// NewDoTTransport creates a DoT transport, tlsConfig may be nil to verify against the system roots
+func NewDoTTransport(timeout time.Duration, tlsConfig *tls.Config) *DoTTransport {
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	return &DoTTransport{Client: &dns.Client{Net: "tcp-tls", Timeout: timeout, TLSConfig: tlsConfig}}
+}
+
+func (t *DoTTransport) Address(server string) string {
+	return withPort(server, "853")
+}
+
+func (t *DoTTransport) DefaultServers() []string {
+	return []string{"1.1.1.1:853", "8.8.8.8:853"}
+}
+
+func (t *DoTTransport) Exchange(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, error) {
+	host, _, _ := net.SplitHostPort(address)
+	client := *t.Client
+	client.TLSConfig = t.Client.TLSConfig.Clone()
+	if client.TLSConfig.ServerName == "" {
+		client.TLSConfig.ServerName = host
+	}
+	resp, _, err := client.ExchangeContext(ctx, msg, address)
+	return resp, err
+}
+
+// DoHTransport queries over DNS-over-HTTPS (RFC 8484) using the wire format with POST or GET
+type DoHTransport struct {
+	Client *http.Client
+	Method string
+}
+
+const dnsMessageContentType = "application/dns-message"
+
+func NewDoHTransport(timeout time.Duration, method string) *DoHTransport {
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: timeout,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConnsPerHost: 10,
+	}
+	return &DoHTransport{Client: &http.Client{Transport: transport, Timeout: timeout}, Method: method}
+}
+
+func (t *DoHTransport) Address(server string) string {
+	if strings.HasPrefix(server, "https://") || strings.HasPrefix(server, "http://") {
+		return server
+	}
+	return "https://" + server + "/dns-query"
+}
+
+func (t *DoHTransport) DefaultServers() []string {
+	return []string{"https://1.1.1.1/dns-query", "https://8.8.8.8/dns-query"}
+}
+
+func (t *DoHTransport) Exchange(ctx context.Context, msg *dns.Msg, address string) (*dns.Msg, error) {
+	// The ID should be 0 so responses are cache friendly
+	q := msg.Copy()
+	q.Id = 0
+	packed, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var req *http.Request
+	if t.Method == http.MethodGet {
+		var u *url.URL
+		if u, err = url.Parse(address); err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		query.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+		u.RawQuery = query.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(packed))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.Method == http.MethodPost {
+		req.Header.Set("Content-Type", dnsMessageContentType)
+	}
+	req.Header.Set("Accept", dnsMessageContentType)
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, err
+	}
+	r.Id = msg.Id
+	return r, nil
+}
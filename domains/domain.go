@@ -1,8 +1,10 @@
 package domains
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,31 +13,77 @@ import (
 )
 
 type Domain struct {
-	DomainName            string                 `json:"domainName,omitempty"`
-	CreatedAt             time.Time              `json:"createdAt,omitempty"`
-	UpdatedAt             time.Time              `json:"updatedAt,omitempty"`
-	NonPublicDomain       bool                   `json:"nonPublicDomain,omitempty"`
-	Hostname              string                 `json:"hostname,omitempty"`
-	Subdomain             string                 `json:"subdomain,omitempty"`
-	Suffix                string                 `json:"suffix,omitempty"`
-	SuccessfulWebLanding  bool                   `json:"successfulWebLanding,omitempty"`
-	WebRedirectURLFinal   string                 `json:"webRedirectURLFinal,omitempty"`
-	LastRanWebRedirect    time.Time              `json:"lastRanWebRedirect,omitempty"`
-	LastRanDns            time.Time              `json:"lastRanDNS,omitempty"`
-	LastRanCertSans       time.Time              `json:"lastRanCertSANs,omitempty"`
-	LastRanSitemapParse   time.Time              `json:"lastRanSitemapParse,omitempty"`
-	ARecords              []ARecord              `json:"aRecords"`
-	AAAARecords           []AAAARecord           `json:"aaaaRecords"`
-	MXRecords             []MXRecord             `json:"mxRecords"`
-	SOARecords            []SOARecord            `json:"soaRecords"`
-	Sitemaps              []*Sitemap             `json:"sitemaps"`
-	WebRedirectDomains    []WebRedirectDomain    `json:"webRedirectDomains"`
-	CertSANs              []CertSansDomain       `json:"certSANs"`
-	SitemapWebDomains     []SitemapWebDomain     `json:"sitemapWebDomains"`
-	SitemapContactDomains []SitemapContactDomain `json:"sitemapContactDomains"`
+	DomainName string `json:"domainName,omitempty"`
+	// DomainNameUnicode is the Unicode form of the ASCII (punycode) DomainName
+	DomainNameUnicode      string                  `json:"domainNameUnicode,omitempty"`
+	CreatedAt              time.Time               `json:"createdAt,omitempty"`
+	UpdatedAt              time.Time               `json:"updatedAt,omitempty"`
+	NonPublicDomain        bool                    `json:"nonPublicDomain,omitempty"`
+	Hostname               string                  `json:"hostname,omitempty"`
+	Subdomain              string                  `json:"subdomain,omitempty"`
+	Suffix                 string                  `json:"suffix,omitempty"`
+	SuccessfulWebLanding   bool                    `json:"successfulWebLanding,omitempty"`
+	WebRedirectURLFinal    string                  `json:"webRedirectURLFinal,omitempty"`
+	LastRanWebRedirect     time.Time               `json:"lastRanWebRedirect,omitempty"`
+	LastRanDns             time.Time               `json:"lastRanDNS,omitempty"`
+	LastRanCertSans        time.Time               `json:"lastRanCertSANs,omitempty"`
+	LastRanSitemapParse    time.Time               `json:"lastRanSitemapParse,omitempty"`
+	LastRanWhois           time.Time               `json:"lastRanWhois,omitempty"`
+	LastRanSpf             time.Time               `json:"lastRanSpf,omitempty"`
+	LastRanDmarc           time.Time               `json:"lastRanDmarc,omitempty"`
+	LastRanCT              time.Time               `json:"lastRanCT,omitempty"`
+	ARecords               []ARecord               `json:"aRecords"`
+	AAAARecords            []AAAARecord            `json:"aaaaRecords"`
+	MXRecords              []MXRecord              `json:"mxRecords"`
+	SOARecords             []SOARecord             `json:"soaRecords"`
+	NSRecords              []NSRecord              `json:"nsRecords"`
+	TXTRecords             []TXTRecord             `json:"txtRecords"`
+	CNAMERecords           []CNAMERecord           `json:"cnameRecords"`
+	CAARecords             []CAARecord             `json:"caaRecords"`
+	SRVRecords             []SRVRecord             `json:"srvRecords"`
+	Sitemaps               []*Sitemap              `json:"sitemaps"`
+	WebRedirectDomains     []WebRedirectDomain     `json:"webRedirectDomains"`
+	CertSANs               []CertSansDomain        `json:"certSANs"`
+	Certificate            *Certificate            `json:"certificate,omitempty"`
+	CertOrgDomains         []CertOrgDomain         `json:"certOrgDomains"`
+	CTLogDomains           []CTLogDomain           `json:"ctLogDomains"`
+	SitemapWebDomains      []SitemapWebDomain      `json:"sitemapWebDomains"`
+	SitemapContactDomains  []SitemapContactDomain  `json:"sitemapContactDomains"`
+	Whois                  *WhoisRecord            `json:"whois,omitempty"`
+	WhoisRegistrantDomains []WhoisRegistrantDomain `json:"whoisRegistrantDomains"`
+	SpfIncludeDomains      []SpfIncludeDomain      `json:"spfIncludeDomains"`
+	Dmarc                  *DmarcRecord            `json:"dmarc,omitempty"`
+	DKIMRecords            []DKIMRecord            `json:"dkimRecords"`
+	DmarcReportDomains     []DmarcReportDomain     `json:"dmarcReportDomains"`
+	SharedIPDomains        []SharedIPDomain        `json:"sharedIPDomains"`
+	SharedMXDomains        []SharedMXDomain        `json:"sharedMXDomains"`
+	SharedNSDomains        []SharedNSDomain        `json:"sharedNSDomains"`
+	MailProvider           string                  `json:"mailProvider,omitempty"`
+	// EnrichmentErrors holds the error of each strategy whose last run failed
+	EnrichmentErrors []EnrichmentError `json:"enrichmentErrors,omitempty"`
+	DNSHost          string            `json:"dnsHost,omitempty"`
+	// StrategyResults holds the results of the strategies added with RegisterEnricher
+	StrategyResults []*StrategyResult `json:"strategyResults,omitempty"`
+	// RedirectChain holds the pages requested following the redirects of the domain, in order
+	RedirectChain []RedirectHop `json:"redirectChain"`
+	// WebLandingVariant is the landing variant, such as https://www., that WebRedirectURLFinal was reached from
+	WebLandingVariant string `json:"webLandingVariant,omitempty"`
+	// HomepageLinkDomains are the domains linked to or loaded from the landing page
+	HomepageLinkDomains  []HomepageLinkDomain `json:"homepageLinkDomains"`
+	LastRanHomepageLinks time.Time            `json:"lastRanHomepageLinks,omitempty"`
+	// TrackerIDs are the analytics and tag IDs of the landing page and its first-party scripts
+	TrackerIDs           []TrackerID           `json:"trackerIDs"`
+	LastRanTrackers      time.Time             `json:"lastRanTrackers,omitempty"`
+	SharedTrackerDomains []SharedTrackerDomain `json:"sharedTrackerDomains"`
+	// Organization is the company the landing page describes itself as
+	Organization          *Organization          `json:"organization,omitempty"`
+	LastRanStructuredData time.Time              `json:"lastRanStructuredData,omitempty"`
+	StructuredDataDomains []StructuredDataDomain `json:"structuredDataDomains"`
 
 	sitemapURLs  []string
 	contactPages []string
+	config       *EnrichmentConfig
+	landing      *landingPage
 
 	*robotstxt.RobotsData
 }
@@ -52,6 +100,7 @@ func (d *Domain) parseDomain() error {
 		return errors.New("Unable to parse domain from public suffix list")
 	}
 	d.DomainName = fmt.Sprintf("%s.%s", strings.ToLower(dom.SLD), strings.ToLower(dom.TLD))
+	d.DomainNameUnicode = UnicodeHost(d.DomainName)
 	d.Hostname = strings.ToLower(dom.SLD)
 	d.Subdomain = strings.ToLower(dom.TRD)
 	d.Suffix = strings.ToLower(dom.TLD)
@@ -59,11 +108,23 @@ func (d *Domain) parseDomain() error {
 	return nil
 }
 
+// FQDN returns the full name the domain was parsed from, subdomain included
+func (d *Domain) FQDN() string {
+	if d.Subdomain == "" {
+		return d.DomainName
+	}
+	return d.Subdomain + "." + d.DomainName
+}
+
+// NewDomain parses the registrable domain of a domain, host, URL or email address, see CanonicalHost
 func NewDomain(domain_name string) (*Domain, error) {
-	dn := strings.TrimSpace(domain_name)
 	now := time.Now()
+	dn, err := CanonicalHost(domain_name)
+	if err != nil {
+		return &Domain{DomainName: strings.TrimSpace(domain_name), CreatedAt: now, UpdatedAt: now}, err
+	}
 	d := &Domain{DomainName: dn, CreatedAt: now, UpdatedAt: now}
-	err := d.parseDomain()
+	err = d.parseDomain()
 	return d, err
 }
 
@@ -72,9 +133,47 @@ type EnrichmentConfig struct {
 	DNS              bool      `json:"dns"`
 	Sitemap          bool      `json:"sitemap"`
 	WebRedirect      bool      `json:"web_redirect"`
+	Whois            bool      `json:"whois"`
+	Spf              bool      `json:"spf"`
+	Dmarc            bool      `json:"dmarc"`
+	CT               bool      `json:"ct"`
+	HomepageLinks    bool      `json:"homepage_links"`
+	Trackers         bool      `json:"trackers"`
+	StructuredData   bool      `json:"structured_data"`
 	MinFreshnessDate time.Time `json:"min_freshness_date"`
+	// Resolvers overrides the default nameservers, tried in order. DoH resolvers are URLs
+	Resolvers    []string      `json:"resolvers,omitempty"`
+	DNSTimeout   time.Duration `json:"dns_timeout,omitempty"`
+	DNSTransport string        `json:"dns_transport,omitempty"`
+	// SpfGenericProviders are flagged as generic in addition to GenericSPFProviders
+	SpfGenericProviders []string `json:"spf_generic_providers,omitempty"`
+	// DkimSelectors replaces DKIMSelectors when set
+	DkimSelectors []string `json:"dkim_selectors,omitempty"`
+	// DmarcVendors are treated as reporting vendors in addition to DmarcVendors
+	DmarcVendors []string `json:"dmarc_vendors,omitempty"`
+	// SharedHostPTRSuffixes and SharedHostCIDRs extend the shared host denylist used to link domains by IP
+	SharedHostPTRSuffixes []string `json:"shared_host_ptr_suffixes,omitempty"`
+	SharedHostCIDRs       []string `json:"shared_host_cidrs,omitempty"`
+	// CertProbeHosts and CertProbePorts replace the default certificate probes when set
+	CertProbeHosts []string `json:"cert_probe_hosts,omitempty"`
+	CertProbePorts []int    `json:"cert_probe_ports,omitempty"`
+	// CTSource is a crt.sh-compatible URL template, a local CT mirror directory or a bulk certificate file
+	CTSource string `json:"ct_source,omitempty"`
+	// CertOrgNormalization controls how certificate subject organizations are matched
+	CertOrgNormalization OrgNormalization `json:"cert_org_normalization"`
+	// DomainTimeout bounds the time spent enriching each domain, strategies not started in time are skipped
+	DomainTimeout time.Duration `json:"domain_timeout,omitempty"`
+	// Strategies enables the strategies added with RegisterEnricher, by name
+	Strategies map[string]bool `json:"strategies,omitempty"`
+	// HTTP tunes the client shared by the web strategies
+	HTTP HTTPConfig `json:"http"`
+	// LandingVariants replaces DefaultLandingVariants when set
+	LandingVariants []string `json:"landing_variants,omitempty"`
 }
 
+// NewEnrichmentConfig enables the original four strategies.
+//
+// Deprecated: build an EnrichmentConfig{} literal and switch strategies on with Enable
 func NewEnrichmentConfig(
 	certSans bool, DNS bool, sitemap bool, webRedirect bool, minFreshnessDate time.Time,
 ) *EnrichmentConfig {
@@ -84,25 +183,48 @@ func NewEnrichmentConfig(
 }
 
 func (d *Domain) Enrich(cfg EnrichmentConfig) {
-	if d.LastRanDns.Unix() <= cfg.MinFreshnessDate.Unix() && cfg.DNS {
-		d.GetDNSRecords()
-	}
-	if d.LastRanWebRedirect.Unix() <= cfg.MinFreshnessDate.Unix() && cfg.WebRedirect {
-		d.GetRedirectDomains()
+	d.EnrichContext(context.Background(), cfg)
+}
+
+// EnrichContext is Enrich, stopping in-flight work once ctx is done or cfg.DomainTimeout has passed
+func (d *Domain) EnrichContext(ctx context.Context, cfg EnrichmentConfig) {
+	if cfg.DomainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.DomainTimeout)
+		defer cancel()
 	}
-	if d.LastRanCertSans.Unix() <= cfg.MinFreshnessDate.Unix() && cfg.CertSans {
-		d.GetCertSANs()
+	d.config = &cfg
+	d.landing = &landingPage{}
+	defer func() { d.landing = nil }()
+	// Strategies not started before ctx is done are left for the next run
+	for _, e := range Enrichers() {
+		if cfg.Enabled(e.Name()) && e.LastRan(d).Unix() <= cfg.MinFreshnessDate.Unix() && ctx.Err() == nil {
+			d.setEnrichmentError(e.Name(), e.Run(ctx, d))
+		}
 	}
-	if d.LastRanSitemapParse.Unix() <= cfg.MinFreshnessDate.Unix() && cfg.Sitemap {
-		d.GetDomainsFromSitemap()
+	if cfg.DNS || cfg.Spf {
+		d.Classify()
 	}
 }
 
 type MatchedDomainsByStrategy struct {
-	WebRedirectDomains    []string `json:"webRedirectDomains"`
-	CertSANs              []string `json:"certSANs"`
-	SitemapWebDomains     []string `json:"sitemapWebDomains"`
-	SitemapContactDomains []string `json:"sitemapContactDomains"`
+	WebRedirectDomains     []string `json:"webRedirectDomains"`
+	CertSANs               []string `json:"certSANs"`
+	SitemapWebDomains      []string `json:"sitemapWebDomains"`
+	SitemapContactDomains  []string `json:"sitemapContactDomains"`
+	WhoisRegistrantDomains []string `json:"whoisRegistrantDomains"`
+	SpfIncludeDomains      []string `json:"spfIncludeDomains"`
+	DmarcReportDomains     []string `json:"dmarcReportDomains"`
+	SharedIPDomains        []string `json:"sharedIPDomains"`
+	SharedMXDomains        []string `json:"sharedMXDomains"`
+	SharedNSDomains        []string `json:"sharedNSDomains"`
+	CertOrgDomains         []string `json:"certOrgDomains"`
+	CTLogDomains           []string `json:"ctLogDomains"`
+	HomepageLinkDomains    []string `json:"homepageLinkDomains"`
+	SharedTrackerDomains   []string `json:"sharedTrackerDomains"`
+	StructuredDataDomains  []string `json:"structuredDataDomains"`
+	// Relationships holds the domains matched by registered strategies, by relationship
+	Relationships map[string][]string `json:"relationships,omitempty"`
 }
 
 func (d *Domain) GetAllMatchedDomains() MatchedDomainsByStrategy {
@@ -119,5 +241,139 @@ func (d *Domain) GetAllMatchedDomains() MatchedDomainsByStrategy {
 	for _, c := range d.SitemapContactDomains {
 		allDomains.SitemapContactDomains = append(allDomains.SitemapContactDomains, c.DomainName)
 	}
+	for _, w := range d.WhoisRegistrantDomains {
+		allDomains.WhoisRegistrantDomains = append(allDomains.WhoisRegistrantDomains, w.DomainName)
+	}
+	for _, s := range d.SpfIncludeDomains {
+		if !s.Generic {
+			allDomains.SpfIncludeDomains = append(allDomains.SpfIncludeDomains, s.DomainName)
+		}
+	}
+	for _, r := range d.DmarcReportDomains {
+		allDomains.DmarcReportDomains = append(allDomains.DmarcReportDomains, r.DomainName)
+	}
+	for _, s := range d.SharedIPDomains {
+		allDomains.SharedIPDomains = append(allDomains.SharedIPDomains, s.DomainName)
+	}
+	for _, s := range d.SharedMXDomains {
+		allDomains.SharedMXDomains = append(allDomains.SharedMXDomains, s.DomainName)
+	}
+	for _, s := range d.SharedNSDomains {
+		allDomains.SharedNSDomains = append(allDomains.SharedNSDomains, s.DomainName)
+	}
+	for _, c := range d.CertOrgDomains {
+		allDomains.CertOrgDomains = append(allDomains.CertOrgDomains, c.DomainName)
+	}
+	for _, c := range d.CTLogDomains {
+		allDomains.CTLogDomains = append(allDomains.CTLogDomains, c.DomainName)
+	}
+	for _, h := range d.HomepageLinkDomains {
+		if !h.Generic {
+			allDomains.HomepageLinkDomains = append(allDomains.HomepageLinkDomains, h.DomainName)
+		}
+	}
+	for _, s := range d.SharedTrackerDomains {
+		allDomains.SharedTrackerDomains = append(allDomains.SharedTrackerDomains, s.DomainName)
+	}
+	for _, s := range d.StructuredDataDomains {
+		if !s.Generic {
+			allDomains.StructuredDataDomains = append(allDomains.StructuredDataDomains, s.DomainName)
+		}
+	}
+	rels := make(map[string][]string)
+	for _, e := range RegisteredEnrichers() {
+		for _, rel := range e.Relationships() {
+			rels[rel] = nil
+		}
+	}
+	for _, r := range d.StrategyResults {
+		for _, sd := range r.Domains {
+			rels[sd.Relationship] = append(rels[sd.Relationship], sd.DomainName)
+		}
+	}
+	if len(rels) > 0 {
+		allDomains.Relationships = rels
+	}
 	return allDomains
 }
+
+// matchedDomains returns every matched domain across the strategies, for updating in place
+func (d *Domain) matchedDomains() []*MatchedDomain {
+	var mds []*MatchedDomain
+	for i := range d.WebRedirectDomains {
+		mds = append(mds, &d.WebRedirectDomains[i].MatchedDomain)
+	}
+	for i := range d.CertSANs {
+		mds = append(mds, &d.CertSANs[i].MatchedDomain)
+	}
+	for i := range d.SitemapWebDomains {
+		mds = append(mds, &d.SitemapWebDomains[i].MatchedDomain)
+	}
+	for i := range d.SitemapContactDomains {
+		mds = append(mds, &d.SitemapContactDomains[i].MatchedDomain)
+	}
+	for i := range d.WhoisRegistrantDomains {
+		mds = append(mds, &d.WhoisRegistrantDomains[i].MatchedDomain)
+	}
+	for i := range d.SpfIncludeDomains {
+		mds = append(mds, &d.SpfIncludeDomains[i].MatchedDomain)
+	}
+	for i := range d.DmarcReportDomains {
+		mds = append(mds, &d.DmarcReportDomains[i].MatchedDomain)
+	}
+	for i := range d.SharedIPDomains {
+		mds = append(mds, &d.SharedIPDomains[i].MatchedDomain)
+	}
+	for i := range d.SharedMXDomains {
+		mds = append(mds, &d.SharedMXDomains[i].MatchedDomain)
+	}
+	for i := range d.SharedNSDomains {
+		mds = append(mds, &d.SharedNSDomains[i].MatchedDomain)
+	}
+	for i := range d.CertOrgDomains {
+		mds = append(mds, &d.CertOrgDomains[i].MatchedDomain)
+	}
+	for i := range d.CTLogDomains {
+		mds = append(mds, &d.CTLogDomains[i].MatchedDomain)
+	}
+	for i := range d.HomepageLinkDomains {
+		mds = append(mds, &d.HomepageLinkDomains[i].MatchedDomain)
+	}
+	for i := range d.SharedTrackerDomains {
+		mds = append(mds, &d.SharedTrackerDomains[i].MatchedDomain)
+	}
+	for i := range d.StructuredDataDomains {
+		mds = append(mds, &d.StructuredDataDomains[i].MatchedDomain)
+	}
+	for _, r := range d.StrategyResults {
+		for i := range r.Domains {
+			mds = append(mds, &r.Domains[i].MatchedDomain)
+		}
+	}
+	return mds
+}
+
+// GetAllMatchedHosts maps each matched domain to the hosts it was observed on, across all strategies
+func (d *Domain) GetAllMatchedHosts() map[string][]string {
+	hosts := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, md := range d.matchedDomains() {
+		for _, h := range md.Hosts {
+			if !seen[md.DomainName+" "+h] {
+				seen[md.DomainName+" "+h] = true
+				hosts[md.DomainName] = append(hosts[md.DomainName], h)
+			}
+		}
+	}
+	for _, hs := range hosts {
+		sort.Strings(hs)
+	}
+	return hosts
+}
+
+// StripHosts drops the observed hosts from every matched domain
+func (d *Domain) StripHosts() {
+	for _, md := range d.matchedDomains() {
+		md.Hosts = nil
+	}
+}
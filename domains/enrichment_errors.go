@@ -0,0 +1,147 @@
+package domains
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Strategy names match the EnrichmentConfig JSON fields that enable them
+const (
+	StrategyDNS            = "dns"
+	StrategyWebRedirect    = "web_redirect"
+	StrategyCertSans       = "cert_sans"
+	StrategySitemap        = "sitemap"
+	StrategyWhois          = "whois"
+	StrategySpf            = "spf"
+	StrategyDmarc          = "dmarc"
+	StrategyCT             = "ct"
+	StrategyHomepageLinks  = "homepage_links"
+	StrategyTrackers       = "trackers"
+	StrategyStructuredData = "structured_data"
+)
+
+// Error classes, from most to least specific
+const (
+	ErrorClassTimeout        = "timeout"
+	ErrorClassCanceled       = "canceled"
+	ErrorClassNXDomain       = "nxdomain"
+	ErrorClassTLS            = "tls"
+	ErrorClassHTTPStatus     = "http_status"
+	ErrorClassRobotsDisallow = "robots_disallow"
+	ErrorClassConnection     = "connection"
+	ErrorClassSkipped        = "skipped"
+	ErrorClassOther          = "other"
+)
+
+var (
+	ErrNonPublicDomain = errors.New("Non public domain")
+	ErrNoWebLanding    = errors.New("DomainName has not successfully landed on the web")
+	ErrNXDomain        = errors.New("received NXDOMAIN")
+	ErrRobotsDisallow  = errors.New("disallowed by robots.txt")
+)
+
+// HTTPStatusError is returned when a fetch answers with an unexpected status code
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("error fetching %s: received status code %d", e.URL, e.StatusCode)
+}
+
+// EnrichmentError records why a strategy failed on its last run. A strategy that succeeds clears its error
+type EnrichmentError struct {
+	Strategy   string    `json:"strategy"`
+	Class      string    `json:"class"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// ClassifyError returns the class of a strategy error. Several strategies aggregate the errors of each server or
+// endpoint they try into one message, so the message is searched when the error chain doesn't say
+func ClassifyError(err error) string {
+	var (
+		netErr    net.Error
+		dnsErr    *net.DNSError
+		statusErr *HTTPStatusError
+		alertErr  tls.AlertError
+		recordErr tls.RecordHeaderError
+		verifyErr *tls.CertificateVerificationError
+		unknownCA x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+		certErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.Is(err, ErrNonPublicDomain), errors.Is(err, ErrNoWebLanding):
+		return ErrorClassSkipped
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, ErrNXDomain), errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return ErrorClassNXDomain
+	case errors.As(err, &alertErr), errors.As(err, &recordErr), errors.As(err, &verifyErr),
+		errors.As(err, &unknownCA), errors.As(err, &hostErr), errors.As(err, &certErr):
+		return ErrorClassTLS
+	case errors.As(err, &statusErr):
+		return ErrorClassHTTPStatus
+	case errors.Is(err, ErrRobotsDisallow):
+		return ErrorClassRobotsDisallow
+	}
+	msg := strings.ToLower(err.Error())
+	for _, c := range []struct {
+		class    string
+		patterns []string
+	}{
+		{ErrorClassTimeout, []string{"timeout", "deadline exceeded", "timed out"}},
+		{ErrorClassCanceled, []string{"context canceled"}},
+		{ErrorClassNXDomain, []string{"nxdomain", "no such host"}},
+		{ErrorClassTLS, []string{"tls:", "x509:", "handshake"}},
+		{ErrorClassHTTPStatus, []string{"status code"}},
+		{ErrorClassRobotsDisallow, []string{"robots.txt"}},
+		{ErrorClassConnection, []string{"connection refused", "connection reset", "no route to host", "eof"}},
+	} {
+		for _, p := range c.patterns {
+			if strings.Contains(msg, p) {
+				return c.class
+			}
+		}
+	}
+	return ErrorClassOther
+}
+
+// setEnrichmentError replaces the recorded error of a strategy, clearing it when err is nil
+func (d *Domain) setEnrichmentError(strategy string, err error) {
+	var errs []EnrichmentError
+	for _, e := range d.EnrichmentErrors {
+		if e.Strategy != strategy {
+			errs = append(errs, e)
+		}
+	}
+	if err != nil {
+		errs = append(
+			errs, EnrichmentError{
+				Strategy: strategy, Class: ClassifyError(err), Message: err.Error(), OccurredAt: time.Now(),
+			},
+		)
+	}
+	d.EnrichmentErrors = errs
+}
+
+// FailedStrategies returns the strategies whose last run failed
+func (d *Domain) FailedStrategies() []string {
+	var strategies []string
+	for _, e := range d.EnrichmentErrors {
+		strategies = append(strategies, e.Strategy)
+	}
+	return strategies
+}
@@ -0,0 +1,77 @@
+package domains
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("failed to make request: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{errors.New("Failed to query all servers: 10.0.0.1:53 (read udp 10.0.0.1:53: i/o timeout)"), ErrorClassTimeout},
+		{fmt.Errorf("failed to make request: %w", context.Canceled), ErrorClassCanceled},
+		{errors.New(`Get "https://acme.com/": context canceled`), ErrorClassCanceled},
+		{fmt.Errorf("acme.com: %w", ErrNXDomain), ErrorClassNXDomain},
+		{&net.DNSError{Err: "no such host", Name: "acme.com", IsNotFound: true}, ErrorClassNXDomain},
+		{tls.AlertError(40), ErrorClassTLS},
+		{x509.UnknownAuthorityError{}, ErrorClassTLS},
+		{errors.New("Failed to probe all endpoints: acme.com:443 (tls: first record does not look like a TLS handshake)"), ErrorClassTLS},
+		{&HTTPStatusError{URL: "https://acme.com/", StatusCode: 503}, ErrorClassHTTPStatus},
+		{fmt.Errorf("Error fetching contact domains: %w", fmt.Errorf("All 2 contact pages in sitemap %w", ErrRobotsDisallow)), ErrorClassRobotsDisallow},
+		{errors.New("dial tcp 127.0.0.1:1: connect: connection refused"), ErrorClassConnection},
+		{ErrNonPublicDomain, ErrorClassSkipped},
+		{ErrNoWebLanding, ErrorClassSkipped},
+		{errors.New("No whois data found for acme.com"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%q) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestEnrichRecordsErrors(t *testing.T) {
+	nxdomain := newDNSServer(
+		t, dns.HandlerFunc(
+			func(w dns.ResponseWriter, r *dns.Msg) {
+				m := new(dns.Msg)
+				m.SetRcode(r, dns.RcodeNameError)
+				w.WriteMsg(m)
+			},
+		),
+	)
+	live := newDNSServer(t, answerA(false))
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EnrichmentErrors = []EnrichmentError{{Strategy: StrategyWhois, Class: ErrorClassTimeout}}
+	d.Enrich(EnrichmentConfig{DNS: true, Resolvers: []string{nxdomain}, DNSTimeout: time.Second})
+	if got := d.FailedStrategies(); len(got) != 2 || got[0] != StrategyWhois || got[1] != StrategyDNS {
+		t.Fatalf("FailedStrategies() = %v, want [whois dns]", got)
+	}
+	if e := d.EnrichmentErrors[1]; e.Class != ErrorClassNXDomain || e.OccurredAt.IsZero() {
+		t.Errorf("DNS error = %+v, want an nxdomain error", e)
+	}
+
+	d.Enrich(
+		EnrichmentConfig{
+			DNS: true, Resolvers: []string{live}, DNSTimeout: time.Second, MinFreshnessDate: time.Now().Add(time.Hour),
+		},
+	)
+	if got := d.FailedStrategies(); len(got) != 1 || got[0] != StrategyWhois {
+		t.Errorf("FailedStrategies() after a successful DNS run = %v, want [whois]", got)
+	}
+}
@@ -1,6 +1,8 @@
 package domains
 
 import (
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -8,4 +10,46 @@ type MatchedDomain struct {
 	CreatedAt  time.Time `json:"createdAt,omitempty"`
 	UpdatedAt  time.Time `json:"updatedAt,omitempty"`
 	DomainName string    `json:"matchedDomain,omitempty"`
+	// Hosts are the fully qualified names the relationship was observed on, DomainName is their registrable domain
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// MaxMatchedHosts caps the hosts kept per matched domain
+var MaxMatchedHosts = 25
+
+// AddHost records a host the match was observed on
+func (m *MatchedDomain) AddHost(host string) {
+	host = strings.TrimPrefix(normalizeHost(host), "*.")
+	if host == "" || len(m.Hosts) >= MaxMatchedHosts {
+		return
+	}
+	for _, h := range m.Hosts {
+		if h == host {
+			return
+		}
+	}
+	m.Hosts = append(m.Hosts, host)
+}
+
+// DomainIndex maps a relationship key (a registrant, an IP address, ...) to the names of the
+// previously enriched domains that carry it
+type DomainIndex map[string]map[string]bool
+
+func (idx DomainIndex) Add(key, domainName string) {
+	if key == "" || domainName == "" {
+		return
+	}
+	if _, ok := idx[key]; !ok {
+		idx[key] = make(map[string]bool)
+	}
+	idx[key][domainName] = true
+}
+
+func (idx DomainIndex) Lookup(key string) []string {
+	var doms []string
+	for dn := range idx[key] {
+		doms = append(doms, dn)
+	}
+	sort.Strings(doms)
+	return doms
 }
@@ -0,0 +1,39 @@
+package domains
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchedDomainHosts(t *testing.T) {
+	doms, hosts := sanDomains(
+		[]string{"acme.com", "*.acme.com", "benefits.partner.com", "*.Partner.com", "benefits.partner.com"}, "acme.com",
+	)
+	if !reflect.DeepEqual(doms, []string{"partner.com"}) {
+		t.Fatalf("sanDomains = %v, want [partner.com]", doms)
+	}
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := CertSansDomain{MatchedDomain: MatchedDomain{DomainName: "partner.com"}}
+	for _, h := range hosts["partner.com"] {
+		cs.AddHost(h)
+	}
+	d.CertSANs = []CertSansDomain{cs}
+	d.SpfIncludeDomains = []SpfIncludeDomain{
+		{MatchedDomain: MatchedDomain{DomainName: "partner.com", Hosts: []string{"_spf.partner.com"}}},
+	}
+
+	want := map[string][]string{"partner.com": {"_spf.partner.com", "benefits.partner.com", "partner.com"}}
+	if got := d.GetAllMatchedHosts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllMatchedHosts() = %v, want %v", got, want)
+	}
+	if got := d.GetAllMatchedDomains().CertSANs; !reflect.DeepEqual(got, []string{"partner.com"}) {
+		t.Errorf("CertSANs = %v, want [partner.com]", got)
+	}
+	d.StripHosts()
+	if got := d.GetAllMatchedHosts(); len(got) != 0 {
+		t.Errorf("GetAllMatchedHosts() after StripHosts = %v, want none", got)
+	}
+}
@@ -0,0 +1,233 @@
+package domains
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Where on the landing page a domain was linked, from strongest to weakest evidence
+const (
+	LinkSectionFooter = "footer"
+	LinkSectionNav    = "nav"
+	LinkSectionBody   = "body"
+	LinkSectionAsset  = "asset"
+)
+
+type HomepageLinkDomain struct {
+	MatchedDomain
+	// Sections are where on the landing page the domain was linked, see LinkSectionFooter
+	Sections []string `json:"sections"`
+	// AnchorTexts are the texts of the links to the domain
+	AnchorTexts []string `json:"anchorTexts,omitempty"`
+	// Generic domains are social networks, CDNs and other services linked from unrelated sites, and are not
+	// evidence of common ownership
+	Generic bool `json:"generic,omitempty"`
+}
+
+// GenericLinkDomains are registrable domains linked from so many sites that their links are recorded but flagged
+// as generic
+var GenericLinkDomains = []string{
+	"facebook.com", "fb.com", "twitter.com", "x.com", "linkedin.com", "instagram.com", "youtube.com", "youtu.be",
+	"tiktok.com", "pinterest.com", "vimeo.com", "google.com", "googleapis.com", "gstatic.com",
+	"googletagmanager.com", "google-analytics.com", "doubleclick.net", "apple.com", "microsoft.com", "bing.com",
+	"cloudflare.com", "cloudflareinsights.com", "jsdelivr.net", "unpkg.com", "jquery.com", "bootstrapcdn.com",
+	"fontawesome.com", "typekit.net", "cookielaw.org", "onetrust.com", "hotjar.com", "hubspot.com", "hs-scripts.com",
+	"wp.com", "wordpress.org", "gravatar.com", "schema.org", "w3.org", "adobe.com", "adobedtm.com", "recaptcha.net",
+}
+
+func isGenericLinkDomain(domainName string) bool {
+	for _, g := range GenericLinkDomains {
+		if g == domainName {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// MaxHomepageLinkDomains caps the domains kept from a landing page
+	MaxHomepageLinkDomains = 200
+	// maxAnchorTexts caps the anchor texts kept per domain
+	maxAnchorTexts = 5
+)
+
+// maxHomepageBytes is how much of the landing page is parsed
+const maxHomepageBytes = 2 << 20
+
+// homepageLink is a link found on the landing page
+type homepageLink struct {
+	href, section, text string
+}
+
+// GetHomepageLinkDomainsContext links the domain to the registrable domains its landing page links to or loads
+// assets from
+func (d *Domain) GetHomepageLinkDomainsContext(ctx context.Context) error {
+	d.LastRanHomepageLinks = time.Now()
+	if !d.SuccessfulWebLanding || d.WebRedirectURLFinal == "" {
+		return ErrNoWebLanding
+	}
+	page, landing, err := d.landingPage(ctx)
+	if err != nil {
+		return err
+	}
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return err
+	}
+
+	created := make(map[string]time.Time)
+	for _, hl := range d.HomepageLinkDomains {
+		created[hl.DomainName] = hl.CreatedAt
+	}
+	now := time.Now()
+	domsFound := make(map[string]int)
+	var links []HomepageLinkDomain
+	for _, l := range homepageLinks(doc, LinkSectionBody, nil) {
+		u, err := landing.Parse(strings.TrimSpace(l.href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		dom, err := NewDomain(u.Hostname())
+		if err != nil || dom.NonPublicDomain || dom.DomainName == d.DomainName {
+			continue
+		}
+		i, exists := domsFound[dom.DomainName]
+		if !exists {
+			if len(links) >= MaxHomepageLinkDomains {
+				continue
+			}
+			links = append(
+				links, HomepageLinkDomain{
+					MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dom.DomainName},
+					Generic:       isGenericLinkDomain(dom.DomainName),
+				},
+			)
+			i = len(links) - 1
+			if c, ok := created[dom.DomainName]; ok {
+				links[i].CreatedAt = c
+			}
+			domsFound[dom.DomainName] = i
+		}
+		hl := &links[i]
+		hl.AddHost(dom.FQDN())
+		hl.addSection(l.section)
+		hl.addAnchorText(l.text)
+	}
+	d.HomepageLinkDomains = links
+	return nil
+}
+
+// homepageLinks walks the page collecting the links of n, classified by the section they are in
+func homepageLinks(n *html.Node, section string, links []homepageLink) []homepageLink {
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "footer":
+			section = LinkSectionFooter
+		case "nav", "header":
+			if section != LinkSectionFooter {
+				section = LinkSectionNav
+			}
+		default:
+			if s := sectionFromAttrs(n); s != "" && section != LinkSectionFooter {
+				section = s
+			}
+		}
+		switch n.Data {
+		case "a":
+			if href := nodeAttr(n, "href"); href != "" {
+				text := nodeText(n)
+				if text == "" {
+					text = nodeAttr(n, "aria-label")
+				}
+				if text == "" {
+					text = nodeAttr(n, "title")
+				}
+				links = append(links, homepageLink{href: href, section: section, text: text})
+			}
+		case "link":
+			if href := nodeAttr(n, "href"); href != "" && !hasToken(nodeAttr(n, "rel"), "canonical") {
+				links = append(links, homepageLink{href: href, section: LinkSectionAsset})
+			}
+		case "script", "iframe":
+			if src := nodeAttr(n, "src"); src != "" {
+				links = append(links, homepageLink{href: src, section: LinkSectionAsset})
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		links = homepageLinks(c, section, links)
+	}
+	return links
+}
+
+// sectionFromAttrs classifies elements such as <div id="site-footer"> or <ul class="main-menu">
+func sectionFromAttrs(n *html.Node) string {
+	names := strings.ToLower(nodeAttr(n, "id") + " " + nodeAttr(n, "class") + " " + nodeAttr(n, "role"))
+	switch {
+	case strings.Contains(names, "footer") || strings.Contains(names, "contentinfo"):
+		return LinkSectionFooter
+	case strings.Contains(names, "nav") || strings.Contains(names, "menu"):
+		return LinkSectionNav
+	}
+	return ""
+}
+
+func nodeAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the whitespace collapsed text of n, falling back to the alt text of the images it contains
+func nodeText(n *html.Node) string {
+	var text, alt []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			text = append(text, strings.Fields(n.Data)...)
+		case n.Type == html.ElementNode && n.Data == "img":
+			alt = append(alt, strings.Fields(nodeAttr(n, "alt"))...)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	if len(text) == 0 {
+		text = alt
+	}
+	s := strings.Join(text, " ")
+	if r := []rune(s); len(r) > 100 {
+		s = string(r[:100])
+	}
+	return s
+}
+
+func (hl *HomepageLinkDomain) addSection(section string) {
+	for _, s := range hl.Sections {
+		if s == section {
+			return
+		}
+	}
+	hl.Sections = append(hl.Sections, section)
+}
+
+func (hl *HomepageLinkDomain) addAnchorText(text string) {
+	if text == "" || len(hl.AnchorTexts) >= maxAnchorTexts {
+		return
+	}
+	for _, t := range hl.AnchorTexts {
+		if strings.EqualFold(t, text) {
+			return
+		}
+	}
+	hl.AnchorTexts = append(hl.AnchorTexts, text)
+}
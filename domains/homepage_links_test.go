@@ -0,0 +1,86 @@
+package domains
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetHomepageLinkDomains(t *testing.T) {
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Host != "www.acme.com" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write(
+					[]byte(`<html><head>
+<link rel="stylesheet" href="https://static.acme-cdn.net/site.css">
+<link rel="canonical" href="https://www.acme-canonical.com/">
+<script src="//www.googletagmanager.com/gtm.js"></script>
+</head><body>
+<header><a href="https://shop.acme-store.com/">Shop</a></header>
+<ul class="main-menu"><li><a href="http://careers.acme-jobs.com/">Careers</a></li></ul>
+<p>Read about <a href="https://news.example.org/acme">our   story</a> and <a href="/about">us</a>.</p>
+<a href="mailto:info@acme-mail.com">Email</a>
+<iframe src="https://player.acme-video.com/embed/1"></iframe>
+<div id="site-footer">
+	<a href="https://www.acme-group.com/"><img alt="Acme Group"></a>
+	<a href="https://acme-group.com/brands" title="Our brands"></a>
+	<a href="https://www.facebook.com/acme">Facebook</a>
+	<nav><a href="https://acme-store.com/help">Help</a></nav>
+</div>
+</body></html>`),
+				)
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{HTTP: HTTPConfig{Proxy: proxy.URL}}
+	if err := d.GetHomepageLinkDomains(); err != ErrNoWebLanding {
+		t.Errorf("GetHomepageLinkDomains() before landing = %v, want ErrNoWebLanding", err)
+	}
+	d.SuccessfulWebLanding, d.WebRedirectURLFinal = true, "http://www.acme.com/"
+	if err := d.GetHomepageLinkDomains(); err != nil {
+		t.Fatal(err)
+	}
+
+	type link struct {
+		sections, texts []string
+		generic         bool
+	}
+	got := make(map[string]link)
+	for _, hl := range d.HomepageLinkDomains {
+		got[hl.DomainName] = link{hl.Sections, hl.AnchorTexts, hl.Generic}
+	}
+	want := map[string]link{
+		"acme-cdn.net":         {[]string{LinkSectionAsset}, nil, false},
+		"googletagmanager.com": {[]string{LinkSectionAsset}, nil, true},
+		"acme-store.com":       {[]string{LinkSectionNav, LinkSectionFooter}, []string{"Shop", "Help"}, false},
+		"acme-jobs.com":        {[]string{LinkSectionNav}, []string{"Careers"}, false},
+		"example.org":          {[]string{LinkSectionBody}, []string{"our story"}, false},
+		"acme-video.com":       {[]string{LinkSectionAsset}, nil, false},
+		"acme-group.com":       {[]string{LinkSectionFooter}, []string{"Acme Group", "Our brands"}, false},
+		"facebook.com":         {[]string{LinkSectionFooter}, []string{"Facebook"}, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HomepageLinkDomains = %+v, want %+v", got, want)
+	}
+
+	matched := d.GetAllMatchedDomains().HomepageLinkDomains
+	for _, m := range matched {
+		if m == "facebook.com" || m == "googletagmanager.com" {
+			t.Errorf("GetAllMatchedDomains() matched generic domain %s", m)
+		}
+	}
+	if len(matched) != 6 {
+		t.Errorf("GetAllMatchedDomains() matched %v, want the 6 non-generic domains", matched)
+	}
+}
@@ -0,0 +1,222 @@
+package domains
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Defaults of the HTTP client shared by the web strategies
+var (
+	DefaultHTTPTimeout         = 10 * time.Second
+	DefaultHTTPDialTimeout     = 5 * time.Second
+	DefaultHTTPMaxBodyBytes    = int64(10 << 20)
+	DefaultHTTPMaxConnsPerHost = 4
+	DefaultUserAgent           = "Mozilla/5.0 (compatible; domwalk/1.0)"
+)
+
+// ErrBodyTooLarge is returned when reading a response body past HTTPConfig.MaxBodyBytes
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// HTTPConfig tunes the HTTP client used by the web strategies (redirects, sitemaps, robots.txt, contact pages,
+// RDAP and CT). Zero values use the defaults
+type HTTPConfig struct {
+	// Timeout bounds each request, body included
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// DialTimeout bounds connecting and the TLS handshake
+	DialTimeout  time.Duration `json:"dial_timeout,omitempty"`
+	MaxBodyBytes int64         `json:"max_body_bytes,omitempty"`
+	UserAgent    string        `json:"user_agent,omitempty"`
+	// Proxy is the URL of the proxy to use instead of HTTP_PROXY and HTTPS_PROXY
+	Proxy string `json:"proxy,omitempty"`
+	// CABundle is a PEM file of certificates trusted in addition to the system roots
+	CABundle string `json:"ca_bundle,omitempty"`
+	// MaxConnsPerHost is the number of idle connections kept open to each host
+	MaxConnsPerHost int `json:"max_conns_per_host,omitempty"`
+}
+
+func (c HTTPConfig) withDefaults() HTTPConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultHTTPTimeout
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = DefaultHTTPDialTimeout
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = DefaultHTTPMaxBodyBytes
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent
+	}
+	if c.MaxConnsPerHost <= 0 {
+		c.MaxConnsPerHost = DefaultHTTPMaxConnsPerHost
+	}
+	return c
+}
+
+var (
+	httpClientsMut sync.Mutex
+	httpClients    = make(map[HTTPConfig]*http.Client)
+)
+
+// NewHTTPClient returns the client for cfg. Clients are shared between calls with the same config so that
+// connections are reused across domains; copy the client before changing it
+func NewHTTPClient(cfg HTTPConfig) (*http.Client, error) {
+	cfg = cfg.withDefaults()
+	httpClientsMut.Lock()
+	defer httpClientsMut.Unlock()
+	if c, ok := httpClients[cfg]; ok {
+		return c, nil
+	}
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", cfg.Proxy, err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	var tlsConfig *tls.Config
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA bundle %s", cfg.CABundle)
+		}
+		tlsConfig = &tls.Config{RootCAs: roots}
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout, // Maximum amount of time to wait for a dial to complete
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.DialTimeout, // Maximum amount of time to wait for a TLS handshake
+		ExpectContinueTimeout: 1 * time.Second, // Maximum amount of time to wait for a 100-continue response from the server
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	c := &http.Client{
+		Transport: &limitedTransport{base: transport, userAgent: cfg.UserAgent, maxBodyBytes: cfg.MaxBodyBytes},
+		Timeout:   cfg.Timeout,
+	}
+	httpClients[cfg] = c
+	return c, nil
+}
+
+// httpClient returns the shared client for the enrichment config
+func (d *Domain) httpClient() (*http.Client, error) {
+	if d.config != nil {
+		return NewHTTPClient(d.config.HTTP)
+	}
+	return NewHTTPClient(HTTPConfig{})
+}
+
+// getPage fetches a page with the shared client, failing on any status but 200. The caller closes the body
+func (d *Domain) getPage(ctx context.Context, u string) (*http.Response, error) {
+	client, err := d.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &HTTPStatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// landingPage is the landing page shared by the web strategies of one EnrichContext run
+type landingPage struct {
+	fetched bool
+	url     string
+	body    []byte
+	final   *url.URL
+	err     error
+}
+
+// landingPage returns up to maxHomepageBytes of the page at WebRedirectURLFinal and the URL it was served from.
+// Within EnrichContext the page is fetched once and shared, otherwise it is fetched on every call
+func (d *Domain) landingPage(ctx context.Context) ([]byte, *url.URL, error) {
+	if l := d.landing; l != nil && l.fetched && l.url == d.WebRedirectURLFinal {
+		return l.body, l.final, l.err
+	}
+	var body []byte
+	var final *url.URL
+	resp, err := d.getPage(ctx, d.WebRedirectURLFinal)
+	if err == nil {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxHomepageBytes))
+		resp.Body.Close()
+		final = resp.Request.URL
+	}
+	if d.landing != nil {
+		*d.landing = landingPage{fetched: true, url: d.WebRedirectURLFinal, body: body, final: final, err: err}
+	}
+	return body, final, err
+}
+
+// limitedTransport sets the User-Agent of requests that don't have one and caps the size of response bodies
+type limitedTransport struct {
+	base         http.RoundTripper
+	userAgent    string
+	maxBodyBytes int64
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: t.maxBodyBytes}
+	return resp, nil
+}
+
+// limitedBody fails reads once more than remaining bytes have been read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n - int(-b.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,160 @@
+package domains
+
+import (
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.UserAgent() + strings.Repeat(".", 100)))
+			},
+		),
+	)
+	defer srv.Close()
+
+	c, err := NewHTTPClient(HTTPConfig{UserAgent: "acme-bot", MaxBodyBytes: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := NewHTTPClient(HTTPConfig{UserAgent: "acme-bot", MaxBodyBytes: 50}); same != c {
+		t.Error("NewHTTPClient returned a new client for the same config")
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !errors.Is(err, ErrBodyTooLarge) || len(body) != 50 {
+		t.Errorf("read %d bytes, %v, want 50 bytes and ErrBodyTooLarge", len(body), err)
+	}
+	if !strings.HasPrefix(string(body), "acme-bot.") {
+		t.Errorf("body = %q, want the acme-bot User-Agent echoed", body)
+	}
+
+	c, _ = NewHTTPClient(HTTPConfig{})
+	resp, err = c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || !strings.HasPrefix(string(body), DefaultUserAgent) {
+		t.Errorf("body = %q, %v, want the default User-Agent echoed", body, err)
+	}
+}
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, pemBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := NewHTTPClient(HTTPConfig{})
+	if _, err := c.Get(srv.URL); err == nil || ClassifyError(err) != ErrorClassTLS {
+		t.Errorf("Get without the CA bundle = %v, want a tls error", err)
+	}
+	c, err := NewHTTPClient(HTTPConfig{CABundle: bundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(srv.URL); err != nil {
+		t.Errorf("Get with the CA bundle: %v", err)
+	}
+	if _, err := NewHTTPClient(HTTPConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("NewHTTPClient accepted a missing CA bundle")
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				proxied = r.URL.String()
+			},
+		),
+	)
+	defer proxy.Close()
+
+	c, err := NewHTTPClient(HTTPConfig{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("http://acme.com/robots.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if proxied != "http://acme.com/robots.txt" {
+		t.Errorf("proxy received %q, want http://acme.com/robots.txt", proxied)
+	}
+}
+
+func TestLandingPageShared(t *testing.T) {
+	var fetches int
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				w.Write(
+					[]byte(`<html><head><title>Acme</title><script>ga('create', 'UA-1234567-2', 'auto');</script>
+</head><body><footer><a href="https://acme-partner.com/">Partner</a></footer></body></html>`),
+				)
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SuccessfulWebLanding, d.WebRedirectURLFinal = true, "http://www.acme.com/"
+	cfg := EnrichmentConfig{
+		HomepageLinks: true, Trackers: true, StructuredData: true, HTTP: HTTPConfig{Proxy: proxy.URL},
+	}
+	d.Enrich(cfg)
+	if fetches != 1 {
+		t.Errorf("landing page fetched %d times in one run, want 1", fetches)
+	}
+	if len(d.HomepageLinkDomains) != 1 || len(d.TrackerIDs) != 1 || d.Organization == nil {
+		t.Errorf(
+			"HomepageLinkDomains = %v, TrackerIDs = %v, Organization = %v", d.HomepageLinkDomains, d.TrackerIDs,
+			d.Organization,
+		)
+	}
+	// Each run fetches the page afresh
+	cfg.MinFreshnessDate = time.Now()
+	d.Enrich(cfg)
+	if fetches != 2 {
+		t.Errorf("landing page fetched %d times in two runs, want 2", fetches)
+	}
+
+	// Strategies that found no landing page still record that they ran
+	d, err = NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Enrich(cfg)
+	if d.LastRanHomepageLinks.IsZero() || d.LastRanTrackers.IsZero() || d.LastRanStructuredData.IsZero() {
+		t.Errorf(
+			"LastRan = %v, %v, %v without a landing page", d.LastRanHomepageLinks, d.LastRanTrackers,
+			d.LastRanStructuredData,
+		)
+	}
+}
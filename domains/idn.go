@@ -0,0 +1,56 @@
+package domains
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// idnProfile applies UTS-46 lookup mapping. Underscores (_spf, _dmarc) and double hyphens outside of xn-- labels
+// are common in real host names, so neither is rejected
+var idnProfile = idna.New(
+	idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false), idna.ValidateLabels(false),
+)
+
+// CanonicalHost reduces a domain, host, URL or email address to its lowercase ASCII (punycode) host name
+func CanonicalHost(input string) (string, error) {
+	host := strings.Trim(input, " \t\r\n,;<>()\"'")
+	if i := strings.Index(host, "://"); i >= 0 || strings.HasPrefix(host, "//") {
+		if i < 0 {
+			host = "http:" + host
+		}
+		u, err := url.Parse(host)
+		if err != nil {
+			return "", err
+		}
+		host = u.Hostname()
+	} else {
+		host = strings.TrimPrefix(host, "mailto:")
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		if i := strings.IndexAny(host, "/?#"); i >= 0 {
+			host = host[:i]
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	host = strings.TrimRight(host, ".")
+	if host == "" {
+		return "", errors.New("No host name in " + input)
+	}
+	return idnProfile.ToASCII(host)
+}
+
+// UnicodeHost returns the Unicode form of an ASCII host name, or the name unchanged if it isn't valid punycode
+func UnicodeHost(host string) string {
+	u, err := idnProfile.ToUnicode(host)
+	if err != nil {
+		return host
+	}
+	return u
+}
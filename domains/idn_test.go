@@ -0,0 +1,48 @@
+package domains
+
+import "testing"
+
+func TestCanonicalHost(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{" Example.COM. ", "example.com"},
+		{"Bücher.de", "xn--bcher-kva.de"},
+		{"XN--BCHER-KVA.DE", "xn--bcher-kva.de"},
+		{"ＥＸＡＭＰＬＥ．com", "example.com"},
+		{"straße.de", "xn--strae-oqa.de"},
+		{"https://www.Bücher.de:8443/path?q=1", "www.xn--bcher-kva.de"},
+		{"//cdn.example.com/app.js", "cdn.example.com"},
+		{"mailto:Jobs@Example.com", "example.com"},
+		{" sales@例子.中国,", "xn--fsqu00a.xn--fiqs8s"},
+		{"www.example.com:443", "www.example.com"},
+		{"_spf.google.com", "_spf.google.com"},
+		{"r3---sn-abc.googlevideo.com", "r3---sn-abc.googlevideo.com"},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalHost(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("CanonicalHost(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "https://", "user@", "xn--zz.com"} {
+		if got, err := CanonicalHost(input); err == nil {
+			t.Errorf("CanonicalHost(%q) = %q, want an error", input, got)
+		}
+	}
+}
+
+func TestNewDomainIDN(t *testing.T) {
+	for _, input := range []string{"shop.bücher.de", "SHOP.XN--BCHER-KVA.DE.", "https://shop.bücher.de/"} {
+		d, err := NewDomain(input)
+		if err != nil {
+			t.Fatalf("NewDomain(%q): %v", input, err)
+		}
+		if d.DomainName != "xn--bcher-kva.de" || d.DomainNameUnicode != "bücher.de" || d.FQDN() != "shop.xn--bcher-kva.de" {
+			t.Errorf(
+				"NewDomain(%q) = %s (%s, %s), want xn--bcher-kva.de (bücher.de, shop.xn--bcher-kva.de)",
+				input, d.DomainName, d.DomainNameUnicode, d.FQDN(),
+			)
+		}
+	}
+}
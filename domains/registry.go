@@ -0,0 +1,273 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Enricher is an enrichment strategy. Strategies other than the built-in ones are added with RegisterEnricher,
+// usually from an init func, and are then run by Enrich, offered as CLI flags, returned in the JSON response and
+// stored in BigQuery without further changes
+type Enricher interface {
+	// Name identifies the strategy in EnrichmentConfig.Strategies and EnrichmentErrors. Its CLI flag is the name
+	// with underscores replaced by dashes
+	Name() string
+	// Description is the help text of the strategy's CLI flag
+	Description() string
+	// LastRan returns when the strategy last ran on d, it is run again once older than MinFreshnessDate
+	LastRan(d *Domain) time.Time
+	// Run enriches d, stopping once ctx is done
+	Run(ctx context.Context, d *Domain) error
+	// Relationships are the names of the relationships the strategy matches domains by
+	Relationships() []string
+}
+
+// builtinEnricher adapts the strategies of Domain to Enricher
+type builtinEnricher struct {
+	name          string
+	description   string
+	relationships []string
+	// flag overrides the CLI flag of strategies whose flag predates their name
+	flag string
+	// enabled returns the EnrichmentConfig field switching the strategy on
+	enabled func(cfg *EnrichmentConfig) *bool
+	lastRan func(d *Domain) time.Time
+	run     func(d *Domain, ctx context.Context) error
+}
+
+// Flag returns the CLI flag of the strategy
+func (e *builtinEnricher) Flag() string {
+	if e.flag != "" {
+		return e.flag
+	}
+	return strings.ReplaceAll(e.name, "_", "-")
+}
+
+func (e *builtinEnricher) Name() string                { return e.name }
+func (e *builtinEnricher) Description() string         { return e.description }
+func (e *builtinEnricher) LastRan(d *Domain) time.Time { return e.lastRan(d) }
+func (e *builtinEnricher) Relationships() []string     { return e.relationships }
+
+func (e *builtinEnricher) Run(ctx context.Context, d *Domain) error {
+	return e.run(d, ctx)
+}
+
+// builtinEnrichers are run in order, before any registered strategy
+var builtinEnrichers = []Enricher{
+	&builtinEnricher{
+		name:          StrategyDNS,
+		description:   "Enrich domains with dns data",
+		relationships: []string{"sharedIPDomains", "sharedMXDomains", "sharedNSDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.DNS },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanDns },
+		run: func(d *Domain, ctx context.Context) error {
+			return errors.Join(d.GetDNSRecordsContext(ctx)...)
+		},
+	},
+	&builtinEnricher{
+		name:          StrategyWebRedirect,
+		description:   "Enrich domains with web redirects",
+		relationships: []string{"webRedirectDomains"},
+		flag:          "web-redirects",
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.WebRedirect },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanWebRedirect },
+		run:           (*Domain).GetRedirectDomainsContext,
+	},
+	&builtinEnricher{
+		name:          StrategyCertSans,
+		description:   "Enrich domains with cert SANs",
+		relationships: []string{"certSANs", "certOrgDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.CertSans },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanCertSans },
+		run:           (*Domain).GetCertSANsContext,
+	},
+	&builtinEnricher{
+		name:          StrategySitemap,
+		description:   "Enrich domains with sitemap web domains",
+		relationships: []string{"sitemapWebDomains", "sitemapContactDomains"},
+		flag:          "sitemaps",
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.Sitemap },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanSitemapParse },
+		run:           (*Domain).GetDomainsFromSitemapContext,
+	},
+	&builtinEnricher{
+		name:          StrategyHomepageLinks,
+		description:   "Enrich domains with domains linked from their landing page",
+		relationships: []string{"homepageLinkDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.HomepageLinks },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanHomepageLinks },
+		run:           (*Domain).GetHomepageLinkDomainsContext,
+	},
+	&builtinEnricher{
+		name:          StrategyTrackers,
+		description:   "Enrich domains with landing page analytics and tag IDs and domains sharing their accounts",
+		relationships: []string{"sharedTrackerDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.Trackers },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanTrackers },
+		run:           (*Domain).GetTrackerIDsContext,
+	},
+	&builtinEnricher{
+		name:          StrategyStructuredData,
+		description:   "Enrich domains with the organization and related domains in their landing page structured data",
+		relationships: []string{"structuredDataDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.StructuredData },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanStructuredData },
+		run:           (*Domain).GetStructuredDataContext,
+	},
+	&builtinEnricher{
+		name:          StrategyWhois,
+		description:   "Enrich domains with WHOIS/RDAP registration data",
+		relationships: []string{"whoisRegistrantDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.Whois },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanWhois },
+		run:           (*Domain).GetWhoisDataContext,
+	},
+	&builtinEnricher{
+		name:          StrategySpf,
+		description:   "Enrich domains with domains included by their SPF records",
+		relationships: []string{"spfIncludeDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.Spf },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanSpf },
+		run:           (*Domain).GetSPFIncludeDomainsContext,
+	},
+	&builtinEnricher{
+		name:          StrategyDmarc,
+		description:   "Enrich domains with DMARC and DKIM records and third party DMARC report domains",
+		relationships: []string{"dmarcReportDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.Dmarc },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanDmarc },
+		run:           (*Domain).GetDmarcReportDomainsContext,
+	},
+	&builtinEnricher{
+		name:          StrategyCT,
+		description:   "Enrich domains with domains named on their Certificate Transparency logged certificates",
+		relationships: []string{"ctLogDomains"},
+		enabled:       func(cfg *EnrichmentConfig) *bool { return &cfg.CT },
+		lastRan:       func(d *Domain) time.Time { return d.LastRanCT },
+		run:           (*Domain).GetCTLogDomainsContext,
+	},
+}
+
+var (
+	registryMut sync.RWMutex
+	registered  []Enricher
+)
+
+// RegisterEnricher adds a strategy, run after the built-in ones in the order registered. It panics if the name is
+// empty or already taken, as for database/sql drivers
+func RegisterEnricher(e Enricher) {
+	registryMut.Lock()
+	defer registryMut.Unlock()
+	if e == nil || e.Name() == "" {
+		panic("domains: RegisterEnricher called with a nil or unnamed strategy")
+	}
+	for _, r := range append(append([]Enricher{}, builtinEnrichers...), registered...) {
+		if r.Name() == e.Name() {
+			panic("domains: RegisterEnricher called twice for strategy " + e.Name())
+		}
+	}
+	registered = append(registered, e)
+}
+
+// Enrichers returns every strategy, built-in ones first
+func Enrichers() []Enricher {
+	registryMut.RLock()
+	defer registryMut.RUnlock()
+	return append(append([]Enricher{}, builtinEnrichers...), registered...)
+}
+
+// RegisteredEnrichers returns the strategies added with RegisterEnricher
+func RegisteredEnrichers() []Enricher {
+	registryMut.RLock()
+	defer registryMut.RUnlock()
+	return append([]Enricher{}, registered...)
+}
+
+// StrategyFlag returns the CLI flag enabling a strategy, its name with underscores replaced by dashes
+func StrategyFlag(e Enricher) string {
+	if f, ok := e.(interface{ Flag() string }); ok {
+		return f.Flag()
+	}
+	return strings.ReplaceAll(e.Name(), "_", "-")
+}
+
+// enabledField returns the field switching on a built-in strategy, or nil for registered strategies
+func (cfg *EnrichmentConfig) enabledField(strategy string) *bool {
+	for _, e := range builtinEnrichers {
+		if b := e.(*builtinEnricher); b.name == strategy {
+			return b.enabled(cfg)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether a strategy is switched on, registered strategies are enabled through Strategies
+func (cfg EnrichmentConfig) Enabled(strategy string) bool {
+	if f := cfg.enabledField(strategy); f != nil {
+		return *f
+	}
+	return cfg.Strategies[strategy]
+}
+
+// Enable switches on a strategy, built-in or registered
+func (cfg *EnrichmentConfig) Enable(strategy string) {
+	if f := cfg.enabledField(strategy); f != nil {
+		*f = true
+		return
+	}
+	if cfg.Strategies == nil {
+		cfg.Strategies = make(map[string]bool)
+	}
+	cfg.Strategies[strategy] = true
+}
+
+// StrategyResult holds what a registered strategy found on a domain
+type StrategyResult struct {
+	Strategy string           `json:"strategy"`
+	LastRan  time.Time        `json:"lastRan,omitempty"`
+	Domains  []StrategyDomain `json:"domains"`
+}
+
+// StrategyDomain is a domain matched by a registered strategy
+type StrategyDomain struct {
+	MatchedDomain
+	Relationship string `json:"relationship"`
+}
+
+// StrategyResult returns the result of a registered strategy on the domain, adding an empty one if it hasn't run
+func (d *Domain) StrategyResult(strategy string) *StrategyResult {
+	for _, r := range d.StrategyResults {
+		if r.Strategy == strategy {
+			return r
+		}
+	}
+	r := &StrategyResult{Strategy: strategy}
+	d.StrategyResults = append(d.StrategyResults, r)
+	return r
+}
+
+// AddDomain records a domain matched by relationship, observed on host. Hosts are reduced to their registrable
+// domain, and self matches and non public hosts are ignored
+func (r *StrategyResult) AddDomain(self *Domain, relationship, host string) {
+	dom, err := NewDomain(host)
+	if err != nil || dom.NonPublicDomain || dom.DomainName == self.DomainName {
+		return
+	}
+	now := time.Now()
+	for i := range r.Domains {
+		if r.Domains[i].Relationship == relationship && r.Domains[i].DomainName == dom.DomainName {
+			r.Domains[i].UpdatedAt = now
+			r.Domains[i].AddHost(dom.FQDN())
+			return
+		}
+	}
+	sd := StrategyDomain{
+		MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dom.DomainName},
+		Relationship:  relationship,
+	}
+	sd.AddHost(dom.FQDN())
+	r.Domains = append(r.Domains, sd)
+}
@@ -0,0 +1,115 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEnricher struct {
+	runs int
+	err  error
+}
+
+func (e *testEnricher) Name() string        { return "test_links" }
+func (e *testEnricher) Description() string { return "Enrich domains with test links" }
+func (e *testEnricher) Relationships() []string {
+	return []string{"testLinkDomains", "testPartnerDomains"}
+}
+
+func (e *testEnricher) LastRan(d *Domain) time.Time {
+	return d.StrategyResult(e.Name()).LastRan
+}
+
+func (e *testEnricher) Run(ctx context.Context, d *Domain) error {
+	e.runs++
+	r := d.StrategyResult(e.Name())
+	r.LastRan = time.Now()
+	r.AddDomain(d, "testLinkDomains", "www.partner.com")
+	r.AddDomain(d, "testLinkDomains", "shop.partner.com")
+	r.AddDomain(d, "testLinkDomains", "www.acme.com")
+	return e.err
+}
+
+func TestRegisterEnricher(t *testing.T) {
+	e := &testEnricher{err: errors.New("No test links found")}
+	RegisterEnricher(e)
+	defer func() { registered = nil }()
+
+	names := []string{}
+	for _, r := range Enrichers() {
+		names = append(names, r.Name())
+	}
+	want := []string{
+		StrategyDNS, StrategyWebRedirect, StrategyCertSans, StrategySitemap, StrategyHomepageLinks,
+		StrategyTrackers, StrategyStructuredData, StrategyWhois, StrategySpf, StrategyDmarc, StrategyCT, "test_links",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Enrichers() = %v, want %v", names, want)
+	}
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Enrich(EnrichmentConfig{})
+	if e.runs != 0 {
+		t.Fatalf("Enrich ran a strategy that isn't enabled")
+	}
+	cfg := EnrichmentConfig{Strategies: map[string]bool{"test_links": true}, MinFreshnessDate: time.Now().Add(-time.Hour)}
+	d.Enrich(cfg)
+	d.Enrich(cfg)
+	if e.runs != 1 {
+		t.Errorf("Enrich ran the strategy %d times, want it skipped once fresh", e.runs)
+	}
+	if got := d.FailedStrategies(); !reflect.DeepEqual(got, []string{"test_links"}) {
+		t.Errorf("FailedStrategies() = %v, want [test_links]", got)
+	}
+
+	matched := d.GetAllMatchedDomains().Relationships
+	if !reflect.DeepEqual(matched, map[string][]string{"testLinkDomains": {"partner.com"}, "testPartnerDomains": nil}) {
+		t.Errorf("GetAllMatchedDomains().Relationships = %v", matched)
+	}
+	hosts := d.GetAllMatchedHosts()["partner.com"]
+	if !reflect.DeepEqual(hosts, []string{"shop.partner.com", "www.partner.com"}) {
+		t.Errorf("GetAllMatchedHosts()[partner.com] = %v", hosts)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterEnricher did not panic on a duplicate name")
+		}
+	}()
+	RegisterEnricher(&testEnricher{})
+}
+
+func TestEnable(t *testing.T) {
+	var cfg EnrichmentConfig
+	for _, e := range append(Enrichers(), &testEnricher{}) {
+		if cfg.Enabled(e.Name()) {
+			t.Errorf("%s enabled in an empty config", e.Name())
+		}
+		cfg.Enable(e.Name())
+		if !cfg.Enabled(e.Name()) {
+			t.Errorf("%s not enabled by Enable", e.Name())
+		}
+	}
+	if !cfg.WebRedirect || !cfg.CT || !reflect.DeepEqual(cfg.Strategies, map[string]bool{"test_links": true}) {
+		t.Errorf("Enable set %+v", cfg)
+	}
+
+	flags := make(map[string]string)
+	for _, e := range append(Enrichers(), &testEnricher{}) {
+		flags[e.Name()] = StrategyFlag(e)
+	}
+	for name, want := range map[string]string{
+		StrategyWebRedirect: "web-redirects", StrategySitemap: "sitemaps", StrategyHomepageLinks: "homepage-links",
+		"test_links": "test-links",
+	} {
+		if flags[name] != want {
+			t.Errorf("StrategyFlag(%s) = %s, want %s", name, flags[name], want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package domains
+
+import "time"
+
+type SharedMXDomain struct {
+	MatchedDomain
+	MX string `json:"mx,omitempty"`
+}
+
+type SharedNSDomain struct {
+	MatchedDomain
+	NS string `json:"ns,omitempty"`
+}
+
+var (
+	// MaxSharedInfraDomains is the number of indexed domains on one mail exchanger or nameserver above which
+	// the host is treated as a hosting provider
+	MaxSharedInfraDomains = 25
+)
+
+// SelfHostedMX returns the domain's mail exchangers not run by a provider in Signatures
+func (d *Domain) SelfHostedMX() []string {
+	var hosts []string
+	for _, m := range d.MXRecords {
+		host := normalizeHost(m.Mx)
+		// A null MX (RFC 7505) accepts no mail
+		if host == "" || host == "localhost" || Signatures.MailProviderForMX(host) != "" {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// VanityNS returns the domain's nameservers not run by a provider in Signatures
+func (d *Domain) VanityNS() []string {
+	var hosts []string
+	for _, n := range d.NSRecords {
+		host := normalizeHost(n.NS)
+		if host == "" || Signatures.DNSHostForNS(host) != "" {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// NewSharedInfraIndex indexes enriched domains by their self-hosted mail exchangers and vanity nameservers
+func NewSharedInfraIndex(doms []*Domain) DomainIndex {
+	idx := make(DomainIndex)
+	for _, d := range doms {
+		for _, mx := range d.SelfHostedMX() {
+			idx.Add("mx:"+mx, d.DomainName)
+		}
+		for _, ns := range d.VanityNS() {
+			idx.Add("ns:"+ns, d.DomainName)
+		}
+	}
+	return idx
+}
+
+// sharedHostDomains returns the indexed domains, other than the domain itself, sharing each host
+func (d *Domain) sharedHostDomains(idx DomainIndex, prefix string, hosts []string) map[string]string {
+	found := make(map[string]string)
+	for _, host := range hosts {
+		matched := idx.Lookup(prefix + host)
+		if len(matched) > MaxSharedInfraDomains {
+			continue
+		}
+		for _, dn := range matched {
+			if dn != d.DomainName {
+				found[dn] = host
+			}
+		}
+	}
+	return found
+}
+
+// GetSharedMXDomains links the domain to every indexed domain using one of its self-hosted mail exchangers
+func (d *Domain) GetSharedMXDomains(idx DomainIndex) {
+	domsFound := make(map[string]SharedMXDomain)
+	for _, df := range d.SharedMXDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	for dn, mx := range d.sharedHostDomains(idx, "mx:", d.SelfHostedMX()) {
+		if df, exists := domsFound[dn]; !exists {
+			domsFound[dn] = SharedMXDomain{
+				MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dn}, MX: mx,
+			}
+		} else {
+			df.UpdatedAt = now
+			df.MX = mx
+			domsFound[dn] = df
+		}
+	}
+	var sd []SharedMXDomain
+	for _, df := range domsFound {
+		sd = append(sd, df)
+	}
+	d.SharedMXDomains = sd
+}
+
+// GetSharedNSDomains links the domain to every indexed domain using one of its vanity nameservers
+func (d *Domain) GetSharedNSDomains(idx DomainIndex) {
+	domsFound := make(map[string]SharedNSDomain)
+	for _, df := range d.SharedNSDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	for dn, ns := range d.sharedHostDomains(idx, "ns:", d.VanityNS()) {
+		if df, exists := domsFound[dn]; !exists {
+			domsFound[dn] = SharedNSDomain{
+				MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dn}, NS: ns,
+			}
+		} else {
+			df.UpdatedAt = now
+			df.NS = ns
+			domsFound[dn] = df
+		}
+	}
+	var sd []SharedNSDomain
+	for _, df := range domsFound {
+		sd = append(sd, df)
+	}
+	d.SharedNSDomains = sd
+}
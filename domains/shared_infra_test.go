@@ -0,0 +1,41 @@
+package domains
+
+import "testing"
+
+func TestGetSharedInfraDomains(t *testing.T) {
+	newDom := func(name string, mxs []string, nss []string) *Domain {
+		d, err := NewDomain(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mx := range mxs {
+			d.MXRecords = append(d.MXRecords, MXRecord{Mx: mx})
+		}
+		for _, ns := range nss {
+			d.NSRecords = append(d.NSRecords, NSRecord{NS: ns})
+		}
+		return d
+	}
+	acme := newDom(
+		"acme-corp.com", []string{"mail.acme-corp.com.", "aspmx.l.google.com."},
+		[]string{"ns1.acme-corp.com.", "ns-12.awsdns-01.co.uk."},
+	)
+	sub := newDom("acme-sub.com", []string{"MAIL.acme-corp.com."}, []string{"ns1.acme-corp.com."})
+	other := newDom("other.com", []string{"alt1.aspmx.l.google.com."}, []string{"ns-99.awsdns-01.co.uk."})
+
+	idx := NewSharedInfraIndex([]*Domain{acme, sub, other})
+	acme.GetSharedMXDomains(idx)
+	acme.GetSharedNSDomains(idx)
+	if len(acme.SharedMXDomains) != 1 || acme.SharedMXDomains[0].DomainName != "acme-sub.com" ||
+		acme.SharedMXDomains[0].MX != "mail.acme-corp.com" {
+		t.Errorf("SharedMXDomains = %v, want only acme-sub.com", acme.SharedMXDomains)
+	}
+	if len(acme.SharedNSDomains) != 1 || acme.SharedNSDomains[0].DomainName != "acme-sub.com" {
+		t.Errorf("SharedNSDomains = %v, want only acme-sub.com", acme.SharedNSDomains)
+	}
+	other.GetSharedMXDomains(idx)
+	other.GetSharedNSDomains(idx)
+	if len(other.SharedMXDomains) != 0 || len(other.SharedNSDomains) != 0 {
+		t.Errorf("provider hosted domains should not be linked: %v %v", other.SharedMXDomains, other.SharedNSDomains)
+	}
+}
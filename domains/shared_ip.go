@@ -0,0 +1,212 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type SharedIPDomain struct {
+	MatchedDomain
+	IP string `json:"ip,omitempty"`
+}
+
+// SharedHostDenylist identifies addresses of CDNs and shared hosts, which are not evidence of common ownership
+type SharedHostDenylist struct {
+	PTRSuffixes []string
+	CIDRs       []*net.IPNet
+}
+
+var (
+	// SharedHostPTRSuffixes match reverse DNS names of CDNs, clouds and shared hosting providers
+	SharedHostPTRSuffixes = []string{
+		"akamaitechnologies.com", "akamaiedge.net", "akamai.net", "cloudfront.net", "amazonaws.com",
+		"googleusercontent.com", "1e100.net", "bc.googleusercontent.com", "cloudapp.net", "cloudapp.azure.com",
+		"azurewebsites.net", "fastly.net", "edgecastcdn.net", "incapdns.net", "sucuri.net", "linode.com",
+		"linodeusercontent.com", "your-server.de", "hetzner.com", "ovh.net", "digitaloceanspaces.com",
+		"secureserver.net", "bluehost.com", "hostgator.com", "websitewelcome.com", "unifiedlayer.com",
+		"dreamhost.com", "wpengine.com", "kinsta.cloud", "pantheonsite.io", "squarespace.com", "wixsite.com",
+		"shopify.com", "myshopify.com", "github.io", "netlify.app", "vercel-dns.com", "herokuapp.com",
+		"siteground.com", "hostinger.com", "ionos.com", "1and1.com", "stackpathdns.com", "zscaler.net",
+	}
+	// SharedHostCIDRs are address ranges of CDNs and hosting platforms serving many unrelated sites
+	SharedHostCIDRs = []string{
+		// Cloudflare
+		"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22", "141.101.64.0/18",
+		"108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20", "197.234.240.0/22", "198.41.128.0/17",
+		"162.158.0.0/15", "104.16.0.0/13", "104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22", "2400:cb00::/32",
+		"2606:4700::/32", "2803:f800::/32", "2405:b500::/32", "2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+		// Fastly
+		"151.101.0.0/16", "199.232.0.0/16", "2a04:4e40::/32",
+		// Squarespace, Wix, Shopify, GitHub Pages, Netlify, Vercel
+		"198.185.159.0/24", "198.49.23.0/24", "185.230.60.0/22", "23.227.38.0/23", "185.199.108.0/22",
+		"75.2.60.5/32", "99.83.231.61/32", "76.76.21.0/24",
+	}
+	// MaxSharedIPDomains is the number of indexed domains on one address above which the address is
+	// treated as shared hosting
+	MaxSharedIPDomains = 25
+)
+
+func NewSharedHostDenylist(ptrSuffixes []string, cidrs []string) (*SharedHostDenylist, error) {
+	l := &SharedHostDenylist{}
+	for _, s := range ptrSuffixes {
+		if s = strings.ToLower(strings.Trim(strings.TrimSpace(s), ".")); s != "" {
+			l.PTRSuffixes = append(l.PTRSuffixes, s)
+		}
+	}
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR %q: %v", c, err)
+		}
+		l.CIDRs = append(l.CIDRs, n)
+	}
+	return l, nil
+}
+
+// SharedHostDenylist returns the default denylist extended with the configured PTR suffixes and CIDRs
+func (cfg EnrichmentConfig) SharedHostDenylist() (*SharedHostDenylist, error) {
+	return NewSharedHostDenylist(
+		append(append([]string{}, SharedHostPTRSuffixes...), cfg.SharedHostPTRSuffixes...),
+		append(append([]string{}, SharedHostCIDRs...), cfg.SharedHostCIDRs...),
+	)
+}
+
+// Shared reports whether the address or any of its PTR names belong to a shared host
+func (l *SharedHostDenylist) Shared(ip string, ptrs []string) bool {
+	if addr := net.ParseIP(ip); addr != nil {
+		for _, n := range l.CIDRs {
+			if n.Contains(addr) {
+				return true
+			}
+		}
+	}
+	for _, ptr := range ptrs {
+		ptr = strings.ToLower(strings.TrimSuffix(ptr, "."))
+		for _, suffix := range l.PTRSuffixes {
+			if ptr == suffix || strings.HasSuffix(ptr, "."+suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (d *Domain) lookupPTR(ctx context.Context, ip string) ([]string, error) {
+	arpa, err := dns.ReverseAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(arpa, dns.TypePTR)
+	r, err := d.queryAllServers(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	var ptrs []string
+	for _, ans := range r.Answer {
+		if p, ok := ans.(*dns.PTR); ok {
+			ptrs = append(ptrs, strings.ToLower(strings.TrimSuffix(p.Ptr, ".")))
+		}
+	}
+	return ptrs, nil
+}
+
+// QueryPTRContext looks up the reverse DNS names of the domain's A and AAAA records
+func (d *Domain) QueryPTRContext(ctx context.Context) error {
+	var errs []string
+	for i, a := range d.ARecords {
+		ptrs, err := d.lookupPTR(ctx, a.IP)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		d.ARecords[i].PTR = ptrs
+	}
+	for i, a := range d.AAAARecords {
+		ptrs, err := d.lookupPTR(ctx, a.IPV6)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		d.AAAARecords[i].PTR = ptrs
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error querying PTR records: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// publicIP reports whether the address is routable on the internet. Private, loopback and link-local addresses are
+// reused across unrelated networks
+func publicIP(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil &&
+		!(addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast())
+}
+
+// DedicatedIPs returns the domain's public addresses that are not on the shared host denylist
+func (d *Domain) DedicatedIPs(l *SharedHostDenylist) []string {
+	var ips []string
+	for _, a := range d.ARecords {
+		if publicIP(a.IP) && !l.Shared(a.IP, a.PTR) {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, a := range d.AAAARecords {
+		if publicIP(a.IPV6) && !l.Shared(a.IPV6, a.PTR) {
+			ips = append(ips, a.IPV6)
+		}
+	}
+	return ips
+}
+
+// NewSharedIPIndex indexes enriched domains by their dedicated IP addresses
+func NewSharedIPIndex(doms []*Domain, l *SharedHostDenylist) DomainIndex {
+	idx := make(DomainIndex)
+	for _, d := range doms {
+		for _, ip := range d.DedicatedIPs(l) {
+			idx.Add("ip:"+ip, d.DomainName)
+		}
+	}
+	return idx
+}
+
+// GetSharedIPDomains links the domain to every indexed domain resolving to one of its dedicated addresses.
+// Addresses carrying more than MaxSharedIPDomains domains are skipped as shared hosting
+func (d *Domain) GetSharedIPDomains(idx DomainIndex, l *SharedHostDenylist) {
+	domsFound := make(map[string]SharedIPDomain)
+	for _, df := range d.SharedIPDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	for _, ip := range d.DedicatedIPs(l) {
+		matched := idx.Lookup("ip:" + ip)
+		if len(matched) > MaxSharedIPDomains {
+			continue
+		}
+		for _, dn := range matched {
+			if dn == d.DomainName {
+				continue
+			}
+			if df, exists := domsFound[dn]; !exists {
+				domsFound[dn] = SharedIPDomain{
+					MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dn}, IP: ip,
+				}
+			} else {
+				df.UpdatedAt = now
+				df.IP = ip
+				domsFound[dn] = df
+			}
+		}
+	}
+	var sd []SharedIPDomain
+	for _, df := range domsFound {
+		sd = append(sd, df)
+	}
+	d.SharedIPDomains = sd
+}
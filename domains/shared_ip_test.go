@@ -0,0 +1,95 @@
+package domains
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestQueryPTR(t *testing.T) {
+	addr := newDNSServer(
+		t, dns.HandlerFunc(
+			func(w dns.ResponseWriter, r *dns.Msg) {
+				m := new(dns.Msg)
+				m.SetReply(r)
+				if r.Question[0].Name == "1.2.0.192.in-addr.arpa." {
+					rr, _ := dns.NewRR(r.Question[0].Name + " 300 IN PTR host1.example.com.")
+					m.Answer = append(m.Answer, rr)
+				}
+				w.WriteMsg(m)
+			},
+		),
+	)
+	d, err := NewDomain("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{Resolvers: []string{addr}, DNSTimeout: time.Second}
+	d.ARecords = []ARecord{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}}
+	if err := d.QueryPTR(); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.ARecords[0].PTR) != 1 || d.ARecords[0].PTR[0] != "host1.example.com" {
+		t.Errorf("PTR = %v", d.ARecords[0].PTR)
+	}
+	if len(d.ARecords[1].PTR) != 0 {
+		t.Errorf("PTR = %v, want none", d.ARecords[1].PTR)
+	}
+}
+
+func TestGetSharedIPDomains(t *testing.T) {
+	l, err := EnrichmentConfig{SharedHostPTRSuffixes: []string{"hosting.example"}}.SharedHostDenylist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDom := func(name string, records ...ARecord) *Domain {
+		d, err := NewDomain(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.ARecords = records
+		return d
+	}
+	a := newDom("alpha.com", ARecord{IP: "192.0.2.10"}, ARecord{IP: "104.16.1.1"})
+	b := newDom("beta.com", ARecord{IP: "192.0.2.10"})
+	cdn := newDom("gamma.com", ARecord{IP: "104.16.1.1"})
+	shared := newDom("delta.com", ARecord{IP: "198.51.100.7", PTR: []string{"web7.hosting.example"}})
+	sharedToo := newDom("epsilon.com", ARecord{IP: "198.51.100.7", PTR: []string{"web7.hosting.example"}})
+
+	idx := NewSharedIPIndex([]*Domain{a, b, cdn, shared, sharedToo}, l)
+	a.GetSharedIPDomains(idx, l)
+	if len(a.SharedIPDomains) != 1 || a.SharedIPDomains[0].DomainName != "beta.com" ||
+		a.SharedIPDomains[0].IP != "192.0.2.10" {
+		t.Errorf("SharedIPDomains = %v, want only beta.com", a.SharedIPDomains)
+	}
+	shared.GetSharedIPDomains(idx, l)
+	if len(shared.SharedIPDomains) != 0 {
+		t.Errorf("SharedIPDomains = %v, want none for a denylisted PTR", shared.SharedIPDomains)
+	}
+
+	if _, err := NewSharedHostDenylist(nil, []string{"not-a-cidr"}); err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+}
+
+func TestDedicatedIPs(t *testing.T) {
+	l, err := EnrichmentConfig{}.SharedHostDenylist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDomain("alpha.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.ARecords = []ARecord{
+		{IP: "10.0.0.5"}, {IP: "192.168.1.1"}, {IP: "127.0.0.1"}, {IP: "0.0.0.0"}, {IP: "169.254.1.1"},
+		{IP: "192.0.2.10"},
+	}
+	d.AAAARecords = []AAAARecord{{IPV6: "::1"}, {IPV6: "fe80::1"}, {IPV6: "fd00::1"}, {IPV6: "2001:db8::10"}}
+	want := []string{"192.0.2.10", "2001:db8::10"}
+	if got := d.DedicatedIPs(l); !reflect.DeepEqual(got, want) {
+		t.Errorf("DedicatedIPs() = %v, want %v", got, want)
+	}
+}
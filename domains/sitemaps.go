@@ -1,11 +1,11 @@
 package domains
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -42,25 +42,25 @@ type SitemapWebDomain struct {
 	MatchedDomain
 }
 
-func (d *Domain) GetDomainsFromSitemap() error {
+func (d *Domain) GetDomainsFromSitemapContext(ctx context.Context) error {
+	d.LastRanSitemapParse = time.Now()
 	if !d.SuccessfulWebLanding {
-		return fmt.Errorf("DomainName has not successfully landed on the web")
+		return ErrNoWebLanding
 	}
-	d.LastRanSitemapParse = time.Now()
-	err := d.getRobotstxt()
+	err := d.getRobotstxt(ctx)
 	if err != nil {
-		return fmt.Errorf("Error fetching robots.txt: %v", err)
+		return fmt.Errorf("Error fetching robots.txt: %w", err)
 	}
-	d.getURLsFromSitemaps()
+	d.getURLsFromSitemaps(ctx)
 	d.GetWebDomainsFromSitemap()
-	err = d.GetContactDomainsFromSitemap()
+	err = d.GetContactDomainsFromSitemapContext(ctx)
 	if err != nil {
-		return fmt.Errorf("Error fetching contact domains: %v", err)
+		return fmt.Errorf("Error fetching contact domains: %w", err)
 	}
 	return nil
 }
 
-func (d *Domain) getRobotstxt() error {
+func (d *Domain) getRobotstxt(ctx context.Context) error {
 	url_raw := d.WebRedirectURLFinal
 	url_parsed, err := url.Parse(url_raw)
 	if err != nil {
@@ -70,7 +70,15 @@ func (d *Domain) getRobotstxt() error {
 	if !strings.HasPrefix(host_root, "http") {
 		host_root = "http://" + url_parsed.Host
 	}
-	resp, err := http.Get(host_root + "/robots.txt")
+	client, err := d.httpClient()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, host_root+"/robots.txt", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -91,31 +99,19 @@ func (d *Domain) getRobotstxt() error {
 	return nil
 }
 
-func (s *Sitemap) readSitemap() (URLSet, []*Sitemap, error) {
-	// add proxy
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second, // Maximum amount of time to wait for a dial to complete
-			KeepAlive: 3 * time.Second, // Keep-alive period for an active network connection
-		}).DialContext,
-		TLSHandshakeTimeout:   5 * time.Second, // Maximum amount of time to wait for a TLS handshake
-		ResponseHeaderTimeout: 5 * time.Second, // Maximum amount of time to wait for a server's response headers
-		ExpectContinueTimeout: 1 * time.Second, // Maximum amount of time to wait for a 100-continue response from the server
-	}
-
-	// Create an HTTP client with the custom transport
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   10 * time.Second, // Overall timeout for the request
+func (s *Sitemap) readSitemap(ctx context.Context, client *http.Client) (URLSet, []*Sitemap, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.SitemapLoc, nil)
+	if err != nil {
+		return URLSet{}, nil, err
 	}
-	resp, err := client.Get(s.SitemapLoc)
+	resp, err := client.Do(req)
 	if err != nil {
 		return URLSet{}, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return URLSet{}, nil, fmt.Errorf("error fetching sitemap: received status code %d", resp.StatusCode)
+		return URLSet{}, nil, &HTTPStatusError{URL: s.SitemapLoc, StatusCode: resp.StatusCode}
 	}
 
 	// Read the sitemap
@@ -144,10 +140,10 @@ func (s *Sitemap) readSitemap() (URLSet, []*Sitemap, error) {
 			}
 			s := &Sitemap{SitemapLoc: sitemap.Loc}
 			sms = append(sms, s)
-			if len(sms) > 200 {
+			if len(sms) > 200 || ctx.Err() != nil {
 				break
 			}
-			urls, sitemaps, err := s.readSitemap()
+			urls, sitemaps, err := s.readSitemap(ctx, client)
 			sms = append(sms, sitemaps...)
 			if err != nil {
 				log.Println(err)
@@ -166,7 +162,12 @@ func (s *Sitemap) readSitemap() (URLSet, []*Sitemap, error) {
 	return urlSet, sms, nil
 }
 
-func (d *Domain) getURLsFromSitemaps() {
+func (d *Domain) getURLsFromSitemaps(ctx context.Context) {
+	client, err := d.httpClient()
+	if err != nil {
+		log.Println(err)
+		return
+	}
 	var smsParsed = make(map[string]bool)
 	for _, sf := range d.Sitemaps {
 		smsParsed[sf.SitemapLoc] = true
@@ -176,8 +177,11 @@ func (d *Domain) getURLsFromSitemaps() {
 		if _, exists := smsParsed[sitemap.SitemapLoc]; exists {
 			continue
 		}
+		if ctx.Err() != nil {
+			return
+		}
 		smsParsed[sitemap.SitemapLoc] = true
-		urls, sitemaps, err := sitemap.readSitemap()
+		urls, sitemaps, err := sitemap.readSitemap(ctx, client)
 		if err != nil {
 			log.Println(err)
 		}
@@ -217,7 +221,7 @@ func (d *Domain) GetWebDomainsFromSitemap() {
 			log.Println(err)
 			continue
 		}
-		dom, err := NewDomain(up.Host)
+		dom, err := NewDomain(up.Hostname())
 		if err != nil {
 			log.Println(err)
 			continue
@@ -225,13 +229,13 @@ func (d *Domain) GetWebDomainsFromSitemap() {
 		if d.DomainName == dom.DomainName {
 			continue
 		}
-		if df, exists := domsFound[dom.DomainName]; !exists {
-			sd := SitemapWebDomain{MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dom.DomainName}}
-			domsFound[dom.DomainName] = sd
-		} else {
-			df.UpdatedAt = now
-			domsFound[dom.DomainName] = df
+		df, exists := domsFound[dom.DomainName]
+		if !exists {
+			df = SitemapWebDomain{MatchedDomain{CreatedAt: now, DomainName: dom.DomainName}}
 		}
+		df.UpdatedAt = now
+		df.AddHost(dom.FQDN())
+		domsFound[dom.DomainName] = df
 	}
 	var wd []SitemapWebDomain
 	for _, df := range domsFound {
@@ -240,26 +244,18 @@ func (d *Domain) GetWebDomainsFromSitemap() {
 	d.SitemapWebDomains = wd
 }
 
-func (d *Domain) GetContactDomainsFromSitemap() error {
-	d.getContactPagesFromSitemap()
+func (d *Domain) GetContactDomainsFromSitemapContext(ctx context.Context) error {
+	disallowed := d.getContactPagesFromSitemap()
+	if len(d.contactPages) == 0 && disallowed > 0 {
+		return fmt.Errorf("All %d contact pages in sitemap %w", disallowed, ErrRobotsDisallow)
+	}
 	if len(d.contactPages) == 0 {
 		return fmt.Errorf("No contact pages found in sitemap")
 	}
 
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second, // Maximum amount of time to wait for a dial to complete
-			KeepAlive: 3 * time.Second, // Keep-alive period for an active network connection
-		}).DialContext,
-		TLSHandshakeTimeout:   5 * time.Second, // Maximum amount of time to wait for a TLS handshake
-		ResponseHeaderTimeout: 5 * time.Second, // Maximum amount of time to wait for a server's response headers
-		ExpectContinueTimeout: 1 * time.Second, // Maximum amount of time to wait for a 100-continue response from the server
-	}
-
-	// Create an HTTP client with the custom transport
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   10 * time.Second, // Overall timeout for the request
+	client, err := d.httpClient()
+	if err != nil {
+		return err
 	}
 
 	var domsFound = make(map[string]SitemapContactDomain)
@@ -268,7 +264,15 @@ func (d *Domain) GetContactDomainsFromSitemap() error {
 	}
 	now := time.Now()
 	for _, url := range d.contactPages {
-		resp, err := client.Get(strings.TrimSpace(url))
+		if ctx.Err() != nil {
+			break
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSpace(url), nil)
+		if err != nil {
+			log.Printf("Error creating request: %s\n", err)
+			continue
+		}
+		resp, err := client.Do(req)
 		if err != nil {
 			log.Printf("Error performing GET request: %s\n", err)
 			continue
@@ -290,7 +294,7 @@ func (d *Domain) GetContactDomainsFromSitemap() error {
 		emailRegex := regexp.MustCompile(`(?:^|\s|,|;)([a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.(com|net|org|edu|gov|info|biz|io|co\.uk|co|us|de|ca)(?:\s|,|;|$))`)
 		emails := emailRegex.FindAllString(string(body), -1)
 		for _, email := range emails {
-			dom, err := NewDomain(strings.Split(email, "@")[1])
+			dom, err := NewDomain(email)
 			if err != nil {
 				log.Println(err)
 				continue
@@ -298,13 +302,13 @@ func (d *Domain) GetContactDomainsFromSitemap() error {
 			if d.DomainName == dom.DomainName {
 				continue
 			}
-			if df, exists := domsFound[dom.DomainName]; !exists {
-				sd := SitemapContactDomain{MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dom.DomainName}}
-				domsFound[dom.DomainName] = sd
-			} else {
-				df.UpdatedAt = now
-				domsFound[dom.DomainName] = df
+			df, exists := domsFound[dom.DomainName]
+			if !exists {
+				df = SitemapContactDomain{MatchedDomain{CreatedAt: now, DomainName: dom.DomainName}}
 			}
+			df.UpdatedAt = now
+			df.AddHost(dom.FQDN())
+			domsFound[dom.DomainName] = df
 		}
 	}
 	var cd []SitemapContactDomain
@@ -312,19 +316,24 @@ func (d *Domain) GetContactDomainsFromSitemap() error {
 		cd = append(cd, df)
 	}
 	d.SitemapContactDomains = cd
-	return nil
+	return ctx.Err()
 
 }
 
-func (d *Domain) getContactPagesFromSitemap() {
+// getContactPagesFromSitemap collects the contact pages robots.txt allows, returning how many it disallowed
+func (d *Domain) getContactPagesFromSitemap() int {
+	disallowed := 0
 	for _, url := range d.sitemapURLs {
 		if strings.Contains(url, "contact") {
 			if allow := d.RobotsData.TestAgent(url, "*"); allow {
 				d.contactPages = append(d.contactPages, url)
+			} else {
+				disallowed++
 			}
 		}
 	}
 	if len(d.contactPages) > 10 {
 		d.contactPages = d.contactPages[:10]
 	}
+	return disallowed
 }
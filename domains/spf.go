@@ -0,0 +1,144 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type SpfIncludeDomain struct {
+	MatchedDomain
+	// Include is the host named by the include: or redirect= mechanism
+	Include string `json:"include,omitempty"`
+	// Generic includes belong to shared mail providers and are not evidence of common ownership
+	Generic bool `json:"generic,omitempty"`
+}
+
+// GenericSPFProviders are registrable domains of shared mail services whose SPF includes are
+// recorded but flagged as generic
+var GenericSPFProviders = []string{
+	"google.com", "outlook.com", "microsoft.com", "office365.us", "zoho.com", "zoho.eu", "yahoo.com", "icloud.com",
+	"amazonses.com", "sendgrid.net", "mailgun.org", "mailjet.com", "sparkpostmail.com", "mandrillapp.com",
+	"mcsv.net", "mailchimp.com", "rsgsv.net", "constantcontact.com", "sendinblue.com", "brevo.com",
+	"postmarkapp.com", "mtasv.net", "salesforce.com", "exacttarget.com", "hubspotemail.net", "hubspot.com",
+	"marketo.org", "mktomail.com", "zendesk.com", "freshdesk.com", "helpscoutemail.com", "intercom.io",
+	"pphosted.com", "mimecast.com", "messagelabs.com", "secureserver.net", "emailsrvr.com", "bluehost.com",
+	"hostgator.com", "ovh.com", "ionos.com", "gandi.net", "protonmail.ch", "fastmail.com", "smtp.com",
+	"sailthru.com", "cmail1.com", "createsend.com", "qualtrics.com", "servicenow.com", "docusign.net",
+}
+
+// maxSPFLookups is the RFC 7208 section 4.6.4 limit on mechanisms that cause DNS lookups
+const maxSPFLookups = 10
+
+func (d *Domain) genericSPFProviders() map[string]bool {
+	providers := make(map[string]bool)
+	for _, p := range GenericSPFProviders {
+		providers[p] = true
+	}
+	if d.config != nil {
+		for _, p := range d.config.SpfGenericProviders {
+			providers[strings.ToLower(strings.TrimSpace(p))] = true
+		}
+	}
+	return providers
+}
+
+func (d *Domain) lookupSPF(ctx context.Context, host string) (string, error) {
+	txts, err := d.lookupTXT(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, txt := range txts {
+		if strings.HasPrefix(strings.ToLower(txt), "v=spf1 ") || strings.ToLower(txt) == "v=spf1" {
+			return txt, nil
+		}
+	}
+	return "", nil
+}
+
+// GetSPFIncludeDomainsContext follows the domain's SPF record through include: and redirect= mechanisms
+func (d *Domain) GetSPFIncludeDomainsContext(ctx context.Context) error {
+	d.LastRanSpf = time.Now()
+	if d.NonPublicDomain {
+		return ErrNonPublicDomain
+	}
+	generic := d.genericSPFProviders()
+	domsFound := make(map[string]SpfIncludeDomain)
+	for _, df := range d.SpfIncludeDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	lookups := 0
+	visited := make(map[string]bool)
+	var walkErr error
+	var walk func(host string)
+	walk = func(host string) {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if visited[host] || walkErr != nil {
+			return
+		}
+		visited[host] = true
+		record, err := d.lookupSPF(ctx, host)
+		if err != nil {
+			walkErr = err
+			return
+		}
+		if record == "" {
+			return
+		}
+		var targets []string
+		for _, term := range strings.Fields(record)[1:] {
+			term = strings.ToLower(term)
+			mechanism := strings.TrimLeft(term, "+-~?")
+			name, value, _ := strings.Cut(mechanism, ":")
+			if n, v, ok := strings.Cut(mechanism, "="); ok && n == "redirect" {
+				name, value = n, v
+			}
+			switch name {
+			case "include", "redirect":
+				targets = append(targets, value)
+				lookups++
+			case "a", "mx", "ptr", "exists":
+				lookups++
+			}
+		}
+		if lookups > maxSPFLookups {
+			walkErr = fmt.Errorf("SPF record exceeds the %d DNS lookup limit", maxSPFLookups)
+			return
+		}
+		for _, target := range targets {
+			// Macros can't be expanded without a sender, skip them
+			if target == "" || strings.Contains(target, "%") {
+				continue
+			}
+			dom, err := NewDomain(target)
+			if err != nil {
+				log.Println("Error parsing domain: ", err)
+				continue
+			}
+			if dom.DomainName != d.DomainName {
+				df, exists := domsFound[dom.DomainName]
+				if !exists {
+					df = SpfIncludeDomain{
+						MatchedDomain: MatchedDomain{CreatedAt: now, DomainName: dom.DomainName},
+						Include:       target,
+					}
+				}
+				df.UpdatedAt = now
+				df.Generic = generic[dom.DomainName]
+				df.AddHost(dom.FQDN())
+				domsFound[dom.DomainName] = df
+			}
+			walk(target)
+		}
+	}
+	walk(d.DomainName)
+	var sd []SpfIncludeDomain
+	for _, df := range domsFound {
+		sd = append(sd, df)
+	}
+	d.SpfIncludeDomains = sd
+	return walkErr
+}
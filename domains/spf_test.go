@@ -0,0 +1,73 @@
+package domains
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// answerTXT serves the given TXT records keyed by fully qualified name
+func answerTXT(records map[string]string) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if txt, ok := records[strings.ToLower(q.Name)]; ok && q.Qtype == dns.TypeTXT {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 300},
+				Txt: []string{txt},
+			})
+		}
+		w.WriteMsg(m)
+	}
+}
+
+func TestGetSPFIncludeDomains(t *testing.T) {
+	addr := newDNSServer(
+		t, answerTXT(
+			map[string]string{
+				"acme.com.":             "v=spf1 include:_spf.acme.com include:_spf.google.com redirect=spf.acme-mail.net",
+				"_spf.acme.com.":        "v=spf1 ip4:192.0.2.0/24 include:spf.acme-corp.com ~all",
+				"spf.acme-corp.com.":    "v=spf1 include:_spf.acme.com -all",
+				"_spf.google.com.":      "v=spf1 ip4:198.51.100.0/24 ~all",
+				"spf.acme-mail.net.":    "v=spf1 include:%{i}._ip.%{h}._ehlo.%{d}._spf.vali.email -all",
+				"looped.com.":           "v=spf1 a mx a mx a mx a mx include:looped2.com -all",
+				"looped2.com.":          "v=spf1 a mx a mx include:looped.com -all",
+				"unused.acme-corp.com.": "v=spf1 -all",
+			},
+		),
+	)
+	cfg := &EnrichmentConfig{Resolvers: []string{addr}, DNSTimeout: time.Second}
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = cfg
+	if err := d.GetSPFIncludeDomains(); err != nil {
+		t.Fatal(err)
+	}
+	generic := make(map[string]bool)
+	for _, s := range d.SpfIncludeDomains {
+		generic[s.DomainName] = s.Generic
+	}
+	if len(generic) != 3 || !generic["google.com"] || generic["acme-corp.com"] || generic["acme-mail.net"] {
+		t.Errorf("SpfIncludeDomains = %v", d.SpfIncludeDomains)
+	}
+	matched := d.GetAllMatchedDomains().SpfIncludeDomains
+	sort.Strings(matched)
+	if strings.Join(matched, ",") != "acme-corp.com,acme-mail.net" {
+		t.Errorf("matched SPF domains = %v", matched)
+	}
+
+	looped, err := NewDomain("looped.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	looped.config = cfg
+	if err := looped.GetSPFIncludeDomains(); err == nil {
+		t.Error("expected the SPF lookup limit to be exceeded")
+	}
+}
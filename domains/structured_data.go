@@ -0,0 +1,295 @@
+package domains
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// How the landing page's structured data names a StructuredDataDomain
+const (
+	StructuredDataSameAs             = "same_as"
+	StructuredDataSubOrganization    = "sub_organization"
+	StructuredDataParentOrganization = "parent_organization"
+)
+
+// Organization is the company a landing page describes itself as, from schema.org JSON-LD, OpenGraph and the page
+// title
+type Organization struct {
+	CreatedAt           time.Time         `json:"createdAt,omitempty"`
+	UpdatedAt           time.Time         `json:"updatedAt,omitempty"`
+	Name                string            `json:"name,omitempty"`
+	LegalName           string            `json:"legalName,omitempty"`
+	URL                 string            `json:"url,omitempty"`
+	SameAs              []string          `json:"sameAs,omitempty"`
+	ParentOrganizations []OrganizationRef `json:"parentOrganizations,omitempty"`
+	SubOrganizations    []OrganizationRef `json:"subOrganizations,omitempty"`
+	// SiteName is the og:site_name meta tag
+	SiteName string `json:"siteName,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// OrganizationRef is a parent or sub organization named by an Organization
+type OrganizationRef struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// DisplayName returns the most readable company name found: the JSON-LD name or legal name, the OpenGraph site
+// name, or else the page title
+func (o *Organization) DisplayName() string {
+	if o == nil {
+		return ""
+	}
+	for _, n := range []string{o.Name, o.LegalName, o.SiteName, o.Title} {
+		if n != "" {
+			return n
+		}
+	}
+	return ""
+}
+
+type StructuredDataDomain struct {
+	MatchedDomain
+	// Relationships are how the structured data names the domain, see StructuredDataSameAs
+	Relationships []string `json:"relationships"`
+	// Generic domains, such as the social networks of sameAs profiles, are in GenericLinkDomains
+	Generic bool `json:"generic,omitempty"`
+}
+
+// GetStructuredDataContext reads the organization described by the landing page, linking the domain to its sameAs,
+// parent and sub organization domains
+func (d *Domain) GetStructuredDataContext(ctx context.Context) error {
+	d.LastRanStructuredData = time.Now()
+	if !d.SuccessfulWebLanding || d.WebRedirectURLFinal == "" {
+		return ErrNoWebLanding
+	}
+	page, landing, err := d.landingPage(ctx)
+	if err != nil {
+		return err
+	}
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	org := &Organization{CreatedAt: now, UpdatedAt: now}
+	var ld map[string]any
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if org.Title == "" {
+					org.Title = nodeText(n)
+				}
+			case "meta":
+				prop := nodeAttr(n, "property")
+				if prop == "" {
+					prop = nodeAttr(n, "name")
+				}
+				if org.SiteName == "" && strings.EqualFold(prop, "og:site_name") {
+					org.SiteName = strings.Join(strings.Fields(nodeAttr(n, "content")), " ")
+				}
+			case "script":
+				// Malformed JSON-LD is common and skipped
+				var v any
+				if ld == nil && strings.EqualFold(strings.TrimSpace(nodeAttr(n, "type")), "application/ld+json") &&
+					n.FirstChild != nil && json.Unmarshal([]byte(n.FirstChild.Data), &v) == nil {
+					ld = findOrganization(v)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if ld != nil {
+		org.Name = ldString(ld["name"])
+		org.LegalName = ldString(ld["legalName"])
+		org.URL = ldString(ld["url"])
+		org.SameAs = ldStrings(ld["sameAs"])
+		org.ParentOrganizations = ldRefs(ld["parentOrganization"])
+		org.SubOrganizations = ldRefs(ld["subOrganization"])
+	}
+	if org.DisplayName() == "" {
+		d.Organization = nil
+	} else {
+		if d.Organization != nil && !d.Organization.CreatedAt.IsZero() {
+			org.CreatedAt = d.Organization.CreatedAt
+		}
+		d.Organization = org
+	}
+	d.StructuredDataDomains = d.structuredDataDomains(landing)
+	return nil
+}
+
+// structuredDataDomains returns the other registrable domains named by the organization's sameAs, parent and sub
+// organization URLs
+func (d *Domain) structuredDataDomains(page *url.URL) []StructuredDataDomain {
+	if d.Organization == nil {
+		return nil
+	}
+	created := make(map[string]time.Time)
+	for _, sd := range d.StructuredDataDomains {
+		created[sd.DomainName] = sd.CreatedAt
+	}
+	type ref struct{ relationship, url string }
+	var refs []ref
+	for _, s := range d.Organization.SameAs {
+		refs = append(refs, ref{StructuredDataSameAs, s})
+	}
+	for _, p := range d.Organization.ParentOrganizations {
+		refs = append(refs, ref{StructuredDataParentOrganization, p.URL})
+	}
+	for _, s := range d.Organization.SubOrganizations {
+		refs = append(refs, ref{StructuredDataSubOrganization, s.URL})
+	}
+
+	now := time.Now()
+	domsFound := make(map[string]int)
+	var sds []StructuredDataDomain
+	for _, r := range refs {
+		if r.url == "" {
+			continue
+		}
+		u, err := page.Parse(r.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		dom, err := NewDomain(u.Hostname())
+		if err != nil || dom.NonPublicDomain || dom.DomainName == d.DomainName {
+			continue
+		}
+		i, exists := domsFound[dom.DomainName]
+		if !exists {
+			sds = append(
+				sds, StructuredDataDomain{
+					MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dom.DomainName},
+					Generic:       isGenericLinkDomain(dom.DomainName),
+				},
+			)
+			i = len(sds) - 1
+			if c, ok := created[dom.DomainName]; ok {
+				sds[i].CreatedAt = c
+			}
+			domsFound[dom.DomainName] = i
+		}
+		sds[i].AddHost(dom.FQDN())
+		if !hasString(sds[i].Relationships, r.relationship) {
+			sds[i].Relationships = append(sds[i].Relationships, r.relationship)
+		}
+	}
+	return sds
+}
+
+// findOrganization returns the first Organization or Corporation node of a JSON-LD document, searching @graph and
+// the publisher, mainEntity and about of other nodes
+func findOrganization(v any) map[string]any {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if o := findOrganization(e); o != nil {
+				return o
+			}
+		}
+	case map[string]any:
+		for _, t := range ldStrings(v["@type"]) {
+			// Types may be given as schema:Organization or https://schema.org/Organization
+			if i := strings.LastIndexAny(t, "/:"); i >= 0 {
+				t = t[i+1:]
+			}
+			if t == "Organization" || t == "Corporation" {
+				return v
+			}
+		}
+		for _, k := range []string{"@graph", "publisher", "mainEntity", "about"} {
+			if o := findOrganization(v[k]); o != nil {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// ldString returns a JSON-LD text value, the first of a list or the @value or @id of an object
+func ldString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case []any:
+		for _, e := range v {
+			if s := ldString(e); s != "" {
+				return s
+			}
+		}
+	case map[string]any:
+		if s := ldString(v["@value"]); s != "" {
+			return s
+		}
+		return ldString(v["@id"])
+	}
+	return ""
+}
+
+// ldStrings returns the text values of a JSON-LD value or list
+func ldStrings(v any) []string {
+	var ss []string
+	if l, ok := v.([]any); ok {
+		for _, e := range l {
+			if s := ldString(e); s != "" {
+				ss = append(ss, s)
+			}
+		}
+	} else if s := ldString(v); s != "" {
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+// ldRefs returns the organizations of a JSON-LD value or list, given as objects, names or URLs
+func ldRefs(v any) []OrganizationRef {
+	var items []any
+	if l, ok := v.([]any); ok {
+		items = l
+	} else if v != nil {
+		items = []any{v}
+	}
+	var refs []OrganizationRef
+	for _, e := range items {
+		var r OrganizationRef
+		switch e := e.(type) {
+		case string:
+			if strings.HasPrefix(e, "http://") || strings.HasPrefix(e, "https://") {
+				r.URL = strings.TrimSpace(e)
+			} else {
+				r.Name = ldString(e)
+			}
+		case map[string]any:
+			r = OrganizationRef{Name: ldString(e["name"]), URL: ldString(e["url"])}
+			if r.URL == "" {
+				r.URL = ldString(e["@id"])
+			}
+		}
+		if r != (OrganizationRef{}) {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+func hasString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package domains
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetStructuredData(t *testing.T) {
+	pages := map[string]string{
+		"www.acme.com/": `<html><head><title>Home | Acme</title>
+<meta property="og:site_name" content="Acme Insurance">
+<script type="application/ld+json">{"broken": </script>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+	{"@type": "WebSite", "url": "https://www.acme.com/"},
+	{"@type": ["Corporation"], "@id": "https://www.acme.com/#org", "name": "Acme", "legalName": "Acme Holdings, Inc.",
+	 "url": "https://www.acme.com/",
+	 "sameAs": ["https://www.linkedin.com/company/acme", "https://acme-group.com/about"],
+	 "parentOrganization": {"@type": "Organization", "name": "Acme Group", "url": "https://www.acme-group.com"},
+	 "subOrganization": [{"name": "Acme Life", "url": "https://acmelife.com"}, "Acme Labs"]}
+]}
+</script></head><body></body></html>`,
+		"www.beta.com/": `<html><head><title> Beta
+	Corp </title></head></html>`,
+	}
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if p, ok := pages[r.Host+r.URL.Path]; ok {
+					w.Write([]byte(p))
+					return
+				}
+				http.NotFound(w, r)
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{HTTP: HTTPConfig{Proxy: proxy.URL}}
+	d.SuccessfulWebLanding, d.WebRedirectURLFinal = true, "http://www.acme.com/"
+	if err := d.GetStructuredData(); err != nil {
+		t.Fatal(err)
+	}
+	o := d.Organization
+	if o == nil || o.DisplayName() != "Acme" || o.LegalName != "Acme Holdings, Inc." ||
+		o.SiteName != "Acme Insurance" || o.Title != "Home | Acme" {
+		t.Fatalf("Organization = %+v", o)
+	}
+	parents := []OrganizationRef{{Name: "Acme Group", URL: "https://www.acme-group.com"}}
+	if !reflect.DeepEqual(o.ParentOrganizations, parents) {
+		t.Errorf("ParentOrganizations = %v, want %v", o.ParentOrganizations, parents)
+	}
+	subs := []OrganizationRef{{Name: "Acme Life", URL: "https://acmelife.com"}, {Name: "Acme Labs"}}
+	if !reflect.DeepEqual(o.SubOrganizations, subs) {
+		t.Errorf("SubOrganizations = %v, want %v", o.SubOrganizations, subs)
+	}
+
+	type rel struct {
+		relationships []string
+		generic       bool
+	}
+	got := make(map[string]rel)
+	for _, sd := range d.StructuredDataDomains {
+		got[sd.DomainName] = rel{sd.Relationships, sd.Generic}
+	}
+	want := map[string]rel{
+		"linkedin.com":   {[]string{StructuredDataSameAs}, true},
+		"acme-group.com": {[]string{StructuredDataSameAs, StructuredDataParentOrganization}, false},
+		"acmelife.com":   {[]string{StructuredDataSubOrganization}, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StructuredDataDomains = %v, want %v", got, want)
+	}
+	if m := d.GetAllMatchedDomains().StructuredDataDomains; len(m) != 2 {
+		t.Errorf("GetAllMatchedDomains() = %v, want acme-group.com and acmelife.com", m)
+	}
+
+	// Without JSON-LD the page title names the company
+	b, _ := NewDomain("beta.com")
+	b.config = d.config
+	b.SuccessfulWebLanding, b.WebRedirectURLFinal = true, "http://www.beta.com/"
+	if err := b.GetStructuredData(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Organization.DisplayName() != "Beta Corp" || len(b.StructuredDataDomains) != 0 {
+		t.Errorf("Organization = %+v, StructuredDataDomains = %v, want Beta Corp from the title", b.Organization,
+			b.StructuredDataDomains)
+	}
+}
+
+func TestFindOrganization(t *testing.T) {
+	for doc, want := range map[string]any{
+		`{"@type": "WebSite", "publisher": {"@type": "Organization", "name": "Acme"}}`:   "Acme",
+		`[{"@type": "Product"}, {"@type": "schema:Corporation", "name": ["Acme Corp"]}]`: "Acme Corp",
+		`{"@type": "http://schema.org/Organization", "name": {"@value": "Acme Ltd"}}`:    "Acme Ltd",
+		`{"@type": "Product", "brand": {"@type": "Organization", "name": "Other"}}`:      nil,
+	} {
+		var v any
+		if err := json.Unmarshal([]byte(doc), &v); err != nil {
+			t.Fatal(err)
+		}
+		o := findOrganization(v)
+		if want == nil {
+			if o != nil {
+				t.Errorf("findOrganization(%s) = %v, want none", doc, o)
+			}
+			continue
+		}
+		if o == nil || ldString(o["name"]) != want {
+			t.Errorf("findOrganization(%s) = %v, want %v", doc, o, want)
+		}
+	}
+}
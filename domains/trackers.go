@@ -0,0 +1,216 @@
+package domains
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Tracker types
+const (
+	TrackerGoogleAnalytics  = "google_analytics"
+	TrackerGoogleTagManager = "google_tag_manager"
+	TrackerGoogleAds        = "google_ads"
+	TrackerMetaPixel        = "meta_pixel"
+	TrackerHubSpot          = "hubspot"
+	TrackerAdobeLaunch      = "adobe_launch"
+	TrackerHotjar           = "hotjar"
+	TrackerLinkedIn         = "linkedin_insight"
+	TrackerClarity          = "microsoft_clarity"
+)
+
+// TrackerID is an analytics, tag manager or advertising account ID found on the landing page
+type TrackerID struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// Source is the URL of the page or first-party script the ID was found in
+	Source string `json:"source"`
+}
+
+// Account returns the account the ID belongs to. Universal Analytics properties of one account, such as
+// UA-12345-1 and UA-12345-2, share the account UA-12345
+func (t TrackerID) Account() string {
+	if t.Type == TrackerGoogleAnalytics && strings.HasPrefix(t.ID, "UA-") {
+		if i := strings.LastIndex(t.ID, "-"); i > len("UA-") {
+			return t.ID[:i]
+		}
+	}
+	return t.ID
+}
+
+type SharedTrackerDomain struct {
+	MatchedDomain
+	Tracker string `json:"tracker,omitempty"`
+	Account string `json:"account,omitempty"`
+}
+
+type trackerPattern struct {
+	typ string
+	re  *regexp.Regexp
+}
+
+// trackerPatterns capture the ID of each tracker from page and script text. IDs too short to be distinctive on
+// their own are only matched in the snippet that loads them
+var trackerPatterns = []trackerPattern{
+	{TrackerGoogleAnalytics, regexp.MustCompile(`\b(UA-\d{4,10}-\d{1,4})\b`)},
+	{TrackerGoogleAnalytics, regexp.MustCompile(`(?:gtag/js\?id=|['"]config['"]\s*,\s*['"])(G-[A-Z0-9]{6,12})\b`)},
+	{TrackerGoogleTagManager, regexp.MustCompile(`\b(GTM-[A-Z0-9]{4,9})\b`)},
+	{TrackerGoogleAds, regexp.MustCompile(`\b(AW-\d{8,12})\b`)},
+	{TrackerMetaPixel, regexp.MustCompile(`fbq\(\s*['"]init['"]\s*,\s*['"]?(\d{10,20})`)},
+	{TrackerMetaPixel, regexp.MustCompile(`facebook\.com/tr/?\?id=(\d{10,20})`)},
+	{TrackerHubSpot, regexp.MustCompile(`js(?:-[a-z0-9]+)?\.hs-scripts\.com/(\d{4,10})\.js`)},
+	{TrackerHubSpot, regexp.MustCompile(`hs-analytics\.net/analytics/\d+/(\d{4,10})\.js`)},
+	{TrackerAdobeLaunch, regexp.MustCompile(`assets\.adobedtm\.com/([0-9a-f]{8,})/`)},
+	{TrackerHotjar, regexp.MustCompile(`hjid\s*:\s*(\d{5,10})`)},
+	{TrackerLinkedIn, regexp.MustCompile(`_linkedin_partner_id\s*=\s*['"]?(\d{4,10})`)},
+	{TrackerClarity, regexp.MustCompile(`clarity\.ms/tag/([a-z0-9]{8,12})`)},
+}
+
+var (
+	// MaxTrackerScripts is the number of first-party scripts of the landing page searched for tracker IDs
+	MaxTrackerScripts = 5
+	// MaxSharedTrackerDomains is the number of indexed domains on one tracker account above which the account is
+	// treated as an agency or platform account
+	MaxSharedTrackerDomains = 25
+)
+
+// maxTrackerScanBytes is how much of the landing page and each script is searched for tracker IDs
+const maxTrackerScanBytes = 2 << 20
+
+// GetTrackerIDsContext collects the tracker IDs of the landing page and of the first-party scripts it loads
+func (d *Domain) GetTrackerIDsContext(ctx context.Context) error {
+	d.LastRanTrackers = time.Now()
+	if !d.SuccessfulWebLanding || d.WebRedirectURLFinal == "" {
+		return ErrNoWebLanding
+	}
+	page, landing, err := d.landingPage(ctx)
+	if err != nil {
+		return err
+	}
+	ids := trackerIDs(string(page), landing.String(), nil)
+
+	// Scripts served from the domain or the domain landed on are searched too, as tag snippets are often bundled
+	firstParty := map[string]bool{d.DomainName: true}
+	if ld, err := NewDomain(landing.Hostname()); err == nil {
+		firstParty[ld.DomainName] = true
+	}
+	scripts := 0
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for scripts < MaxTrackerScripts && ctx.Err() == nil {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		t := z.Token()
+		if t.Data != "script" || attr(t, "src") == "" {
+			continue
+		}
+		u, err := landing.Parse(strings.TrimSpace(attr(t, "src")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if sd, err := NewDomain(u.Hostname()); err != nil || !firstParty[sd.DomainName] {
+			continue
+		}
+		scripts++
+		// Scripts that fail to load are skipped, the landing page is the main source
+		sresp, err := d.getPage(ctx, u.String())
+		if err != nil {
+			continue
+		}
+		script, _ := io.ReadAll(io.LimitReader(sresp.Body, maxTrackerScanBytes))
+		sresp.Body.Close()
+		ids = trackerIDs(string(script), u.String(), ids)
+	}
+	d.TrackerIDs = ids
+	return ctx.Err()
+}
+
+// trackerIDs appends the tracker IDs found in text not already in ids
+func trackerIDs(text, source string, ids []TrackerID) []TrackerID {
+	for _, p := range trackerPatterns {
+		for _, m := range p.re.FindAllStringSubmatch(text, -1) {
+			found := false
+			for _, id := range ids {
+				if id.Type == p.typ && id.ID == m[1] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				ids = append(ids, TrackerID{Type: p.typ, ID: m[1], Source: source})
+			}
+		}
+	}
+	return ids
+}
+
+// TrackerAccounts returns the domain's tracker accounts as type:account keys
+func (d *Domain) TrackerAccounts() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, t := range d.TrackerIDs {
+		k := t.Type + ":" + t.Account()
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// NewSharedTrackerIndex indexes enriched domains by their tracker accounts
+func NewSharedTrackerIndex(doms []*Domain) DomainIndex {
+	idx := make(DomainIndex)
+	for _, d := range doms {
+		for _, k := range d.TrackerAccounts() {
+			idx.Add("tracker:"+k, d.DomainName)
+		}
+	}
+	return idx
+}
+
+// GetSharedTrackerDomains links the domain to every indexed domain sharing one of its tracker accounts. Accounts
+// on more than MaxSharedTrackerDomains domains are skipped
+func (d *Domain) GetSharedTrackerDomains(idx DomainIndex) {
+	domsFound := make(map[string]SharedTrackerDomain)
+	for _, df := range d.SharedTrackerDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	for _, k := range d.TrackerAccounts() {
+		matched := idx.Lookup("tracker:" + k)
+		if len(matched) > MaxSharedTrackerDomains {
+			continue
+		}
+		tracker, account, _ := strings.Cut(k, ":")
+		for _, dn := range matched {
+			if dn == d.DomainName {
+				continue
+			}
+			if df, exists := domsFound[dn]; !exists {
+				domsFound[dn] = SharedTrackerDomain{
+					MatchedDomain: MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dn},
+					Tracker:       tracker, Account: account,
+				}
+			} else {
+				df.UpdatedAt = now
+				df.Tracker, df.Account = tracker, account
+				domsFound[dn] = df
+			}
+		}
+	}
+	var sd []SharedTrackerDomain
+	for _, df := range domsFound {
+		sd = append(sd, df)
+	}
+	d.SharedTrackerDomains = sd
+}
@@ -0,0 +1,99 @@
+package domains
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetTrackerIDs(t *testing.T) {
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.Host + r.URL.Path {
+				case "www.acme.com/":
+					w.Write(
+						[]byte(`<html><head>
+<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123XYZ9"></script>
+<script>gtag('config', 'G-ABC123XYZ9'); ga('create', 'UA-1234567-2', 'auto');</script>
+<script>fbq('init', '123456789012345');</script>
+<script src="//js.hs-scripts.com/4567890.js"></script>
+<script src="https://assets.adobedtm.com/5d3f9a1b2c4e/8a7b6c5d4e3f/launch-0a1b2c3d.min.js"></script>
+<script src="/js/app.js"></script>
+<script src="https://cdn.acme-partner.com/widget.js"></script>
+</head><body>Version G-NOTAGTAG1</body></html>`),
+					)
+				case "www.acme.com/js/app.js":
+					w.Write([]byte(`(function(w,d,s,l,i){})(window,document,'script','dataLayer','GTM-K7Q2PZ');`))
+				case "cdn.acme-partner.com/widget.js":
+					w.Write([]byte(`var partner = 'UA-7654321-1';`))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{HTTP: HTTPConfig{Proxy: proxy.URL}}
+	d.SuccessfulWebLanding, d.WebRedirectURLFinal = true, "http://www.acme.com/"
+	if err := d.GetTrackerIDs(); err != nil {
+		t.Fatal(err)
+	}
+	want := []TrackerID{
+		{TrackerGoogleAnalytics, "UA-1234567-2", "http://www.acme.com/"},
+		{TrackerGoogleAnalytics, "G-ABC123XYZ9", "http://www.acme.com/"},
+		{TrackerMetaPixel, "123456789012345", "http://www.acme.com/"},
+		{TrackerHubSpot, "4567890", "http://www.acme.com/"},
+		{TrackerAdobeLaunch, "5d3f9a1b2c4e", "http://www.acme.com/"},
+		{TrackerGoogleTagManager, "GTM-K7Q2PZ", "http://www.acme.com/js/app.js"},
+	}
+	if !reflect.DeepEqual(d.TrackerIDs, want) {
+		t.Errorf("TrackerIDs = %v, want %v", d.TrackerIDs, want)
+	}
+}
+
+func TestGetSharedTrackerDomains(t *testing.T) {
+	newDom := func(name string, ids ...TrackerID) *Domain {
+		d, err := NewDomain(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.TrackerIDs = ids
+		return d
+	}
+	a := newDom("alpha.com", TrackerID{Type: TrackerGoogleAnalytics, ID: "UA-1234567-1"})
+	b := newDom("beta.com", TrackerID{Type: TrackerGoogleAnalytics, ID: "UA-1234567-5"})
+	c := newDom("gamma.com", TrackerID{Type: TrackerGoogleTagManager, ID: "GTM-K7Q2PZ"})
+	e := newDom("delta.com", TrackerID{Type: TrackerMetaPixel, ID: "1234567"})
+	f := newDom("epsilon.com", TrackerID{Type: TrackerHubSpot, ID: "1234567"})
+	doms := []*Domain{a, b, c, e, f}
+
+	idx := NewSharedTrackerIndex(doms)
+	for _, d := range doms {
+		d.GetSharedTrackerDomains(idx)
+	}
+	if len(a.SharedTrackerDomains) != 1 || a.SharedTrackerDomains[0].DomainName != "beta.com" ||
+		a.SharedTrackerDomains[0].Account != "UA-1234567" {
+		t.Errorf("alpha.com SharedTrackerDomains = %+v, want beta.com on UA-1234567", a.SharedTrackerDomains)
+	}
+	// The same number on different trackers is not a shared account
+	if len(c.SharedTrackerDomains)+len(e.SharedTrackerDomains)+len(f.SharedTrackerDomains) != 0 {
+		t.Errorf("unexpected shared tracker domains %+v %+v %+v", c.SharedTrackerDomains, e.SharedTrackerDomains,
+			f.SharedTrackerDomains)
+	}
+
+	old := MaxSharedTrackerDomains
+	MaxSharedTrackerDomains = 1
+	defer func() { MaxSharedTrackerDomains = old }()
+	a.SharedTrackerDomains = nil
+	a.GetSharedTrackerDomains(idx)
+	if len(a.SharedTrackerDomains) != 0 {
+		t.Errorf("SharedTrackerDomains = %+v, want accounts over the limit skipped", a.SharedTrackerDomains)
+	}
+}
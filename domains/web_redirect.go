@@ -1,75 +1,367 @@
 package domains
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/weppos/publicsuffix-go/publicsuffix"
+	"golang.org/x/net/html"
 )
 
 type WebRedirectDomain struct {
 	MatchedDomain
 }
 
-func (d *Domain) GetRedirectDomains() error {
-	d.LastRanWebRedirect = time.Now()
-	hosts := make(map[string]bool)
-	finalURL := fmt.Sprintf("https://%s", d.DomainName)
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second, // Maximum amount of time to wait for a dial to complete
-			KeepAlive: 3 * time.Second, // Keep-alive period for an active network connection
-		}).DialContext,
-		TLSHandshakeTimeout:   5 * time.Second, // Maximum amount of time to wait for a TLS handshake
-		ResponseHeaderTimeout: 5 * time.Second, // Maximum amount of time to wait for a server's response headers
-		ExpectContinueTimeout: 1 * time.Second, // Maximum amount of time to wait for a 100-continue response from the server
-	}
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			dom, err := publicsuffix.ParseFromListWithOptions(
-				publicsuffix.DefaultList, req.URL.Hostname(), &publicsuffix.FindOptions{IgnorePrivate: false},
-			)
-			if err == nil && dom != nil {
-				if dn := fmt.Sprintf("%s.%s", dom.SLD, dom.TLD); dn != d.DomainName {
-					hosts[fmt.Sprintf("%s.%s", dom.SLD, dom.TLD)] = true
-				}
-			}
-			finalURL = req.URL.String()
-			return nil
-		},
-		Transport: transport,
-		Timeout:   10 * time.Second,
+// How a page redirects onwards
+const (
+	RedirectTypeHTTP        = "http"
+	RedirectTypeMetaRefresh = "meta_refresh"
+	RedirectTypeJavaScript  = "javascript"
+	RedirectTypeCanonical   = "canonical"
+	// RedirectTypeJavaScriptHint is a location change inside a larger script, such as a click handler
+	RedirectTypeJavaScriptHint = "javascript_hint"
+)
+
+// followRedirectType reports whether a redirect of the type is followed, canonical links and JavaScript hints are
+// only recorded
+func followRedirectType(typ string) bool {
+	return typ != RedirectTypeCanonical && typ != RedirectTypeJavaScriptHint
+}
+
+// MaxRedirectHops is the number of pages requested from each start before giving up
+var MaxRedirectHops = 10
+
+// DefaultLandingVariants are the starts tried for a domain, in order, until one lands
+var DefaultLandingVariants = []string{"https://", "http://", "https://www.", "http://www."}
+
+// ParseLandingVariant checks a landing variant: http:// or https://, optionally followed by a label and a dot
+// such as www.
+func ParseLandingVariant(v string) (string, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	label := strings.TrimPrefix(strings.TrimPrefix(v, "https://"), "http://")
+	if label == v || (label != "" && (!strings.HasSuffix(label, ".") || strings.ContainsAny(label, "/:?#@"))) {
+		return "", fmt.Errorf(
+			"invalid landing variant %q, must be http:// or https:// optionally followed by a label such as www.", v,
+		)
+	}
+	return v, nil
+}
+
+func (d *Domain) landingVariants() []string {
+	if d.config != nil && len(d.config.LandingVariants) > 0 {
+		return d.config.LandingVariants
 	}
+	return DefaultLandingVariants
+}
+
+// maxRedirectScanBytes is how much of a page is searched for meta refresh, JavaScript and canonical redirects
+const maxRedirectScanBytes = 512 << 10
+
+// RedirectHop is a page requested while following the redirects of a domain
+type RedirectHop struct {
+	// Start is the URL the chain of the hop started from
+	Start      string `json:"start"`
+	URL        string `json:"url"`
+	Method     string `json:"method"`
+	StatusCode int    `json:"statusCode"`
+	// Type is how the page redirects onwards, empty for the landing page. A canonical link is recorded when it
+	// names another host, and a JavaScript hint when a script changes location among other code, but neither is
+	// followed
+	Type string `json:"type,omitempty"`
+	// Location is the Location header, or the target of a meta refresh, JavaScript redirect, canonical link or hint
+	Location string        `json:"location,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+var (
+	jsLocationAssign = regexp.MustCompile(
+		`(?:window\.|document\.|top\.|self\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']`,
+	)
+	jsLocationCall = regexp.MustCompile(`location\.(?:replace|assign)\(\s*["']([^"']+)["']\s*\)`)
+	// jsComment matches block and line comments, the latter only after whitespace or a semicolon so URLs survive
+	jsComment = regexp.MustCompile(`(?s)/\*.*?\*/|(?m)(?:^|[\s;])//.*$`)
+)
 
-	// Make the initial request
-	resp, err := client.Get(fmt.Sprintf("http://%s", d.DomainName))
+// scriptRedirect returns the location a script changes to, and whether the script does nothing else. Redirects
+// behind conditions or in event handlers are in larger scripts
+func scriptRedirect(script string) (string, bool) {
+	for _, re := range []*regexp.Regexp{jsLocationAssign, jsLocationCall} {
+		m := re.FindStringSubmatchIndex(script)
+		if m == nil {
+			continue
+		}
+		rest := script[:m[0]] + script[m[1]:]
+		rest = strings.TrimSpace(strings.Trim(strings.TrimSpace(jsComment.ReplaceAllString(rest, " ")), ";"))
+		return script[m[2]:m[3]], rest == ""
+	}
+	return "", false
+}
+
+func (d *Domain) GetRedirectDomainsContext(ctx context.Context) error {
+	d.LastRanWebRedirect = time.Now()
+	shared, err := d.httpClient()
 	if err != nil {
+		return err
+	}
+	// Redirects are followed by followRedirects to record each hop
+	client := *shared
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	// The first variant answering without an error status lands, or else the first answering at all
+	var (
+		chain          []RedirectHop
+		errs           []error
+		landing        *http.Response
+		landingVariant string
+	)
+	for _, variant := range d.landingVariants() {
+		variant, err := ParseLandingVariant(variant)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		hops, resp, err := followRedirects(ctx, &client, variant+d.DomainName)
+		chain = append(chain, hops...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if landing == nil || (landing.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusBadRequest) {
+			landing, landingVariant = resp, variant
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			break
+		}
+	}
+	d.RedirectChain = chain
+	d.WebRedirectDomains = d.redirectDomains(chain)
+	if landing == nil {
 		d.SuccessfulWebLanding = false
-		d.WebRedirectDomains = []WebRedirectDomain{}
-		return fmt.Errorf("failed to make request: %v", err)
+		d.WebLandingVariant = ""
+		d.WebRedirectURLFinal = ""
+		return fmt.Errorf("failed to make request: %w", errors.Join(errs...))
 	}
-	defer resp.Body.Close()
+	d.SuccessfulWebLanding = true
+	d.WebLandingVariant = landingVariant
+	d.WebRedirectURLFinal = landing.Request.URL.String()
+	// Redirects are still recorded when the landing page errors
+	if landing.StatusCode >= http.StatusBadRequest {
+		return &HTTPStatusError{URL: d.WebRedirectURLFinal, StatusCode: landing.StatusCode}
+	}
+	return nil
+}
 
-	d.WebRedirectURLFinal = finalURL
-	if len(hosts) == 0 {
-		d.SuccessfulWebLanding = true
-		d.WebRedirectDomains = []WebRedirectDomain{}
-		return nil
+// redirectDomains returns the other registrable domains named along the chain
+func (d *Domain) redirectDomains(chain []RedirectHop) []WebRedirectDomain {
+	created := make(map[string]time.Time)
+	for _, wr := range d.WebRedirectDomains {
+		created[wr.DomainName] = wr.CreatedAt
 	}
 	now := time.Now()
+	domsFound := make(map[string]WebRedirectDomain)
+	var order []string
+	for _, hop := range chain {
+		urls := []string{hop.URL, hop.Location}
+		if hop.Type == RedirectTypeJavaScriptHint {
+			// Hints are not evidence the domain sends visitors on
+			urls = urls[:1]
+		}
+		for _, u := range urls {
+			host := hopHost(hop.URL, u)
+			if host == "" {
+				continue
+			}
+			rdom, err := NewDomain(host)
+			if err != nil || rdom.NonPublicDomain || rdom.DomainName == d.DomainName {
+				continue
+			}
+			wr, exists := domsFound[rdom.DomainName]
+			if !exists {
+				wr = WebRedirectDomain{MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: rdom.DomainName}}
+				if c, ok := created[rdom.DomainName]; ok {
+					wr.CreatedAt = c
+				}
+				order = append(order, rdom.DomainName)
+			}
+			wr.AddHost(rdom.FQDN())
+			domsFound[rdom.DomainName] = wr
+		}
+	}
 	wrs := []WebRedirectDomain{}
-	for host := range hosts {
-		rdom, err := NewDomain(host)
+	for _, dn := range order {
+		wrs = append(wrs, domsFound[dn])
+	}
+	return wrs
+}
+
+// hopHost returns the host of ref, resolved against the page it was found on
+func hopHost(page, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	base, err := url.Parse(page)
+	if err != nil {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// followRedirects requests start and follows its HTTP, meta refresh and JavaScript redirects, returning a hop per
+// page requested and the last response, whose body is closed
+func followRedirects(ctx context.Context, client *http.Client, start string) ([]RedirectHop, *http.Response, error) {
+	var hops []RedirectHop
+	visited := make(map[string]bool)
+	next := start
+	for {
+		if len(hops) >= MaxRedirectHops {
+			return hops, nil, fmt.Errorf("stopped after %d redirects", MaxRedirectHops)
+		}
+		visited[next] = true
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
 		if err != nil {
-			log.Println(err)
-			continue
+			return hops, nil, err
+		}
+		begin := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return hops, nil, err
+		}
+		hop := RedirectHop{
+			Start: start, URL: next, Method: req.Method, StatusCode: resp.StatusCode, Duration: time.Since(begin),
+		}
+		var typ, target string
+		if loc, err := resp.Location(); err == nil && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+			typ, target = RedirectTypeHTTP, loc.String()
+			hop.Location = resp.Header.Get("Location")
+		} else if resp.StatusCode == http.StatusOK {
+			typ, target = pageRedirect(resp)
+			hop.Location = target
 		}
-		wr := WebRedirectDomain{MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: rdom.DomainName}}
-		wrs = append(wrs, wr)
+		resp.Body.Close()
+		if typ == "" || (followRedirectType(typ) && visited[target]) {
+			hop.Location = ""
+			return append(hops, hop), resp, nil
+		}
+		hop.Type = typ
+		hops = append(hops, hop)
+		if !followRedirectType(typ) {
+			return hops, resp, nil
+		}
+		next = target
 	}
-	d.WebRedirectDomains = wrs
-	return nil
+}
+
+// pageRedirect searches an HTML page for a meta refresh or a script that only redirects, or else a canonical link
+// to another host or a location change in a larger script, returning its type and absolute URL
+func pageRedirect(resp *http.Response) (string, string) {
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return "", ""
+	}
+	resolve := func(ref string) string {
+		u, err := resp.Request.URL.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return ""
+		}
+		return u.String()
+	}
+	var canonical, hint string
+	inScript := false
+	z := html.NewTokenizer(io.LimitReader(resp.Body, maxRedirectScanBytes))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if canonical != "" {
+				return RedirectTypeCanonical, canonical
+			}
+			if hint != "" {
+				return RedirectTypeJavaScriptHint, hint
+			}
+			return "", ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "meta":
+				if strings.EqualFold(attr(t, "http-equiv"), "refresh") {
+					u := resolve(metaRefreshURL(attr(t, "content")))
+					if u != "" && u != resp.Request.URL.String() {
+						return RedirectTypeMetaRefresh, u
+					}
+				}
+			case "link":
+				if canonical == "" && hasToken(attr(t, "rel"), "canonical") {
+					u := resolve(attr(t, "href"))
+					if u != "" && hopHost(u, u) != resp.Request.URL.Hostname() {
+						canonical = u
+					}
+				}
+			case "script":
+				inScript = t.Type == html.StartTagToken
+			}
+		case html.EndTagToken:
+			inScript = false
+		case html.TextToken:
+			if !inScript {
+				continue
+			}
+			target, only := scriptRedirect(string(z.Text()))
+			if u := resolve(target); target != "" && u != "" {
+				if only {
+					return RedirectTypeJavaScript, u
+				}
+				if hint == "" {
+					hint = u
+				}
+			}
+		}
+	}
+}
+
+// metaRefreshURL returns the URL of a meta refresh content attribute such as "0; url='/home'"
+func metaRefreshURL(content string) string {
+	i := strings.IndexAny(content, ";,")
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimSpace(content[i+1:])
+	if len(rest) < 3 || !strings.EqualFold(rest[:3], "url") {
+		return ""
+	}
+	rest = strings.TrimSpace(rest[3:])
+	if !strings.HasPrefix(rest, "=") {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(rest[1:]), `"'`)
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether a space separated attribute value, such as rel, contains token
+func hasToken(val, token string) bool {
+	for _, f := range strings.Fields(val) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
 }
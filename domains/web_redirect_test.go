@@ -0,0 +1,212 @@
+package domains
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetRedirectDomains(t *testing.T) {
+	// Serves every host, as the proxy of the HTTP client. https starts fail to tunnel
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.Host + r.URL.Path {
+				case "acme.com/":
+					http.Redirect(w, r, "http://www.acme.com/home", http.StatusMovedPermanently)
+				case "www.acme.com/home":
+					w.Write([]byte(`<html><head><meta http-equiv="Refresh" content="0; URL='http://acme-brand.com/'"></head></html>`))
+				case "acme-brand.com/":
+					w.Write([]byte(`<html><script>/* welcome */ window.location.href = "/welcome";</script></html>`))
+				case "acme-brand.com/welcome":
+					w.Write([]byte(`<html><script>location.replace('http://shop.acme-group.com/')</script></html>`))
+				case "shop.acme-group.com/":
+					w.Write(
+						[]byte(`<html><head><link rel="canonical" href="https://www.acme-group.com/"></head></html>`),
+					)
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{HTTP: HTTPConfig{Proxy: proxy.URL}}
+	if err := d.GetRedirectDomains(); err != nil {
+		t.Fatal(err)
+	}
+
+	type hop struct{ url, typ, location string }
+	var got []hop
+	for _, h := range d.RedirectChain {
+		if h.Start != "http://acme.com" || h.Method != http.MethodGet || h.Duration <= 0 {
+			t.Errorf("hop %+v, want a timed GET from http://acme.com", h)
+		}
+		got = append(got, hop{h.URL, h.Type, h.Location})
+	}
+	want := []hop{
+		{"http://acme.com", RedirectTypeHTTP, "http://www.acme.com/home"},
+		{"http://www.acme.com/home", RedirectTypeMetaRefresh, "http://acme-brand.com/"},
+		{"http://acme-brand.com/", RedirectTypeJavaScript, "http://acme-brand.com/welcome"},
+		{"http://acme-brand.com/welcome", RedirectTypeJavaScript, "http://shop.acme-group.com/"},
+		{"http://shop.acme-group.com/", RedirectTypeCanonical, "https://www.acme-group.com/"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedirectChain = %v, want %v", got, want)
+	}
+	if d.RedirectChain[0].StatusCode != http.StatusMovedPermanently || d.RedirectChain[1].StatusCode != http.StatusOK {
+		t.Errorf("status codes = %d, %d, want 301, 200", d.RedirectChain[0].StatusCode, d.RedirectChain[1].StatusCode)
+	}
+	if !d.SuccessfulWebLanding || d.WebRedirectURLFinal != "http://shop.acme-group.com/" {
+		t.Errorf("landed %t on %s, want http://shop.acme-group.com/", d.SuccessfulWebLanding, d.WebRedirectURLFinal)
+	}
+
+	doms := make(map[string][]string)
+	for _, wr := range d.WebRedirectDomains {
+		doms[wr.DomainName] = wr.Hosts
+	}
+	wantDoms := map[string][]string{
+		"acme-brand.com": {"acme-brand.com"},
+		"acme-group.com": {"shop.acme-group.com", "www.acme-group.com"},
+	}
+	if !reflect.DeepEqual(doms, wantDoms) {
+		t.Errorf("WebRedirectDomains = %v, want %v", doms, wantDoms)
+	}
+}
+
+func TestGetRedirectDomainsScriptHint(t *testing.T) {
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`<html><body><button id="shop">Shop</button><script>
+document.getElementById("shop").addEventListener("click", function () {
+	location.href = "https://shop.acme-partner.com/";
+});
+</script></body></html>`))
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{HTTP: HTTPConfig{Proxy: proxy.URL}}
+	if err := d.GetRedirectDomains(); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.RedirectChain) != 1 || d.RedirectChain[0].Type != RedirectTypeJavaScriptHint ||
+		d.RedirectChain[0].Location != "https://shop.acme-partner.com/" {
+		t.Fatalf("RedirectChain = %+v, want the landing page with a javascript_hint", d.RedirectChain)
+	}
+	if d.WebRedirectURLFinal != "http://acme.com" || len(d.WebRedirectDomains) != 0 {
+		t.Errorf(
+			"landed on %s with WebRedirectDomains %v, want http://acme.com and none", d.WebRedirectURLFinal,
+			d.WebRedirectDomains,
+		)
+	}
+}
+
+func TestScriptRedirect(t *testing.T) {
+	tests := []struct {
+		script, target string
+		only           bool
+	}{
+		{`window.location = "/next";`, "/next", true},
+		{"\n  // go home\n  location.replace('http://acme.com/')  \n", "http://acme.com/", true},
+		{`if (true) { location.href = "/a"; }`, "/a", false},
+		{`btn.onclick = function() { location.assign("/b") }`, "/b", false},
+		{`var x = 1;`, "", false},
+	}
+	for _, tt := range tests {
+		if target, only := scriptRedirect(tt.script); target != tt.target || only != tt.only {
+			t.Errorf("scriptRedirect(%q) = %q, %t, want %q, %t", tt.script, target, only, tt.target, tt.only)
+		}
+	}
+}
+
+func TestMetaRefreshURL(t *testing.T) {
+	tests := map[string]string{
+		"0; url=http://acme.com/":  "http://acme.com/",
+		"5;URL='/home'":            "/home",
+		`0, url = "next.html"`:     "next.html",
+		"300":                      "",
+		"0; http://acme.com/":      "",
+		"0; urlx=http://acme.com/": "",
+	}
+	for content, want := range tests {
+		if got := metaRefreshURL(content); got != want {
+			t.Errorf("metaRefreshURL(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestGetRedirectDomainsLandingVariants(t *testing.T) {
+	proxy := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.Host {
+				case "acme.com":
+					w.WriteHeader(http.StatusServiceUnavailable)
+				case "www.acme.com":
+					w.Write([]byte(`<html></html>`))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	defer proxy.Close()
+
+	d, err := NewDomain("acme.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = &EnrichmentConfig{
+		HTTP: HTTPConfig{Proxy: proxy.URL}, LandingVariants: []string{"http://", "HTTP://www.", "http://shop."},
+	}
+	if err := d.GetRedirectDomains(); err != nil {
+		t.Fatal(err)
+	}
+	if d.WebLandingVariant != "http://www." || d.WebRedirectURLFinal != "http://www.acme.com" {
+		t.Errorf(
+			"landed on %s from %s, want http://www.acme.com from http://www.", d.WebRedirectURLFinal, d.WebLandingVariant,
+		)
+	}
+	if len(d.RedirectChain) != 2 || d.RedirectChain[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("RedirectChain = %+v, want the apex 503 then the www landing", d.RedirectChain)
+	}
+
+	d.config.LandingVariants = []string{"http://"}
+	if err := d.GetRedirectDomains(); ClassifyError(err) != ErrorClassHTTPStatus || !d.SuccessfulWebLanding {
+		t.Errorf("GetRedirectDomains() = %v, landed %t, want an http_status error", err, d.SuccessfulWebLanding)
+	}
+	d.config.LandingVariants = []string{"https://"}
+	err = d.GetRedirectDomains()
+	if err == nil || d.SuccessfulWebLanding || d.WebLandingVariant != "" || d.WebRedirectURLFinal != "" {
+		t.Errorf(
+			"GetRedirectDomains() = %v, landed %t on %q, want no landing", err, d.SuccessfulWebLanding,
+			d.WebRedirectURLFinal,
+		)
+	}
+}
+
+func TestParseLandingVariant(t *testing.T) {
+	for _, v := range []string{"https://", "HTTP://", "https://www.", "http://m."} {
+		if _, err := ParseLandingVariant(v); err != nil {
+			t.Errorf("ParseLandingVariant(%q): %v", v, err)
+		}
+	}
+	for _, v := range []string{"", "www.", "ftp://", "https://www", "https://www/.", "https://user@www."} {
+		if got, err := ParseLandingVariant(v); err == nil {
+			t.Errorf("ParseLandingVariant(%q) = %q, want an error", v, got)
+		}
+	}
+}
@@ -1,3 +1,458 @@
 package domains
 
-// TODO: Add whois dom strategy
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WhoisRecord struct {
+	CreatedAt        time.Time `json:"createdAt,omitempty"`
+	UpdatedAt        time.Time `json:"updatedAt,omitempty"`
+	Source           string    `json:"source,omitempty"`
+	Registrar        string    `json:"registrar,omitempty"`
+	RegistrantOrg    string    `json:"registrantOrg,omitempty"`
+	RegistrantEmail  string    `json:"registrantEmail,omitempty"`
+	RegistrationDate time.Time `json:"registrationDate,omitempty"`
+	ExpirationDate   time.Time `json:"expirationDate,omitempty"`
+	NameServers      []string  `json:"nameServers,omitempty"`
+}
+
+type WhoisRegistrantDomain struct {
+	MatchedDomain
+}
+
+const (
+	WhoisSourceRDAP  = "rdap"
+	WhoisSourceWhois = "whois"
+)
+
+var (
+	// RDAPBootstrapURL is the IANA bootstrap registry used to find the RDAP server for a TLD
+	RDAPBootstrapURL = "https://data.iana.org/rdap/dns.json"
+	// WhoisServer is the port-43 server queried first, its referral is followed to the registry
+	WhoisServer = "whois.iana.org:43"
+
+	// RedactedRegistrantPatterns mark registrant values that belong to privacy services or were redacted
+	RedactedRegistrantPatterns = []string{
+		"redacted", "privacy", "private registration", "private by design", "registered privately", "proxy",
+		"withheld", "not disclosed", "data protected", "whoisguard", "statutory masking", "gdpr", "identity protect",
+		"please query", "please contact", "anonymi",
+	}
+	// MaxRegistrantDomains is the number of indexed domains on one registrant above which the registrant is
+	// treated as a reseller or privacy service registering domains for its customers
+	MaxRegistrantDomains = 25
+
+	rdapBootstraps   = make(map[string]map[string]string)
+	rdapBootstrapMut sync.Mutex
+)
+
+func (d *Domain) GetWhoisDataContext(ctx context.Context) error {
+	d.LastRanWhois = time.Now()
+	if d.NonPublicDomain {
+		return ErrNonPublicDomain
+	}
+	client, err := d.httpClient()
+	if err != nil {
+		return err
+	}
+	rec, rdapErr := queryRDAP(ctx, client, d.DomainName, d.Suffix)
+	if rdapErr != nil {
+		rec, err = queryWhois(ctx, d.DomainName)
+		if err != nil {
+			return fmt.Errorf("rdap: %v, whois: %v", rdapErr, err)
+		}
+	}
+	now := time.Now()
+	rec.CreatedAt = now
+	rec.UpdatedAt = now
+	if d.Whois != nil && !d.Whois.CreatedAt.IsZero() {
+		rec.CreatedAt = d.Whois.CreatedAt
+	}
+	d.Whois = rec
+	return nil
+}
+
+func isRedactedRegistrant(v string) bool {
+	v = strings.ToLower(v)
+	for _, p := range RedactedRegistrantPatterns {
+		if strings.Contains(v, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistrantKeys returns the index keys for the non-redacted registrant organization and email
+func (w *WhoisRecord) RegistrantKeys() []string {
+	var keys []string
+	if w == nil {
+		return keys
+	}
+	if org := strings.ToLower(strings.Join(strings.Fields(w.RegistrantOrg), " ")); org != "" && !isRedactedRegistrant(org) {
+		keys = append(keys, "org:"+org)
+	}
+	if email := strings.ToLower(strings.TrimSpace(w.RegistrantEmail)); strings.Contains(email, "@") && !isRedactedRegistrant(email) {
+		keys = append(keys, "email:"+email)
+	}
+	return keys
+}
+
+// NewRegistrantIndex indexes enriched domains by their registrant keys
+func NewRegistrantIndex(doms []*Domain) DomainIndex {
+	idx := make(DomainIndex)
+	for _, d := range doms {
+		for _, k := range d.Whois.RegistrantKeys() {
+			idx.Add(k, d.DomainName)
+		}
+	}
+	return idx
+}
+
+// GetWhoisRegistrantDomains links the domain to every indexed domain sharing its registrant organization or email.
+// Registrants on more than MaxRegistrantDomains domains are skipped
+func (d *Domain) GetWhoisRegistrantDomains(idx DomainIndex) {
+	domsFound := make(map[string]WhoisRegistrantDomain)
+	for _, df := range d.WhoisRegistrantDomains {
+		domsFound[df.DomainName] = df
+	}
+	now := time.Now()
+	for _, k := range d.Whois.RegistrantKeys() {
+		matched := idx.Lookup(k)
+		if len(matched) > MaxRegistrantDomains {
+			continue
+		}
+		for _, dn := range matched {
+			if dn == d.DomainName {
+				continue
+			}
+			if df, exists := domsFound[dn]; !exists {
+				domsFound[dn] = WhoisRegistrantDomain{MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: dn}}
+			} else {
+				df.UpdatedAt = now
+				domsFound[dn] = df
+			}
+		}
+	}
+	var wd []WhoisRegistrantDomain
+	for _, df := range domsFound {
+		wd = append(wd, df)
+	}
+	d.WhoisRegistrantDomains = wd
+}
+
+type rdapBootstrap struct {
+	Services [][][]string `json:"services"`
+}
+
+// rdapServerForTLD returns the RDAP base URL for the given TLD, loading and caching the bootstrap file. The file
+// is fetched without holding the lock, so a slow fetch doesn't block lookups, and failed fetches are retried
+func rdapServerForTLD(ctx context.Context, client *http.Client, tld string) (string, error) {
+	bootstrapURL := RDAPBootstrapURL
+	rdapBootstrapMut.Lock()
+	servers, ok := rdapBootstraps[bootstrapURL]
+	rdapBootstrapMut.Unlock()
+	if !ok {
+		var err error
+		if servers, err = fetchRDAPBootstrap(ctx, client, bootstrapURL); err != nil {
+			return "", err
+		}
+		rdapBootstrapMut.Lock()
+		rdapBootstraps[bootstrapURL] = servers
+		rdapBootstrapMut.Unlock()
+	}
+	// Multi-label suffixes (co.uk) are registered under their last label
+	labels := strings.Split(tld, ".")
+	for i := range labels {
+		if s, ok := servers[strings.Join(labels[i:], ".")]; ok {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("No RDAP server found for %s", tld)
+}
+
+// fetchRDAPBootstrap returns the RDAP base URL of each TLD in a bootstrap file
+func fetchRDAPBootstrap(ctx context.Context, client *http.Client, bootstrapURL string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bootstrapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching rdap bootstrap: received status code %d", resp.StatusCode)
+	}
+	var bs rdapBootstrap
+	if err := json.NewDecoder(resp.Body).Decode(&bs); err != nil {
+		return nil, fmt.Errorf("error parsing rdap bootstrap: %v", err)
+	}
+	servers := make(map[string]string)
+	for _, svc := range bs.Services {
+		if len(svc) < 2 || len(svc[1]) == 0 {
+			continue
+		}
+		for _, t := range svc[0] {
+			servers[strings.ToLower(t)] = svc[1][0]
+		}
+	}
+	return servers, nil
+}
+
+type rdapEntity struct {
+	Roles      []string     `json:"roles"`
+	VcardArray []any        `json:"vcardArray"`
+	Entities   []rdapEntity `json:"entities"`
+}
+
+type rdapDomain struct {
+	Entities []rdapEntity `json:"entities"`
+	Events   []struct {
+		EventAction string `json:"eventAction"`
+		EventDate   string `json:"eventDate"`
+	} `json:"events"`
+	Nameservers []struct {
+		LdhName string `json:"ldhName"`
+	} `json:"nameservers"`
+}
+
+func queryRDAP(ctx context.Context, client *http.Client, domainName, tld string) (*WhoisRecord, error) {
+	base, err := rdapServerForTLD(ctx, client, tld)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(base, "/")+"/domain/"+domainName, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{URL: req.URL.String(), StatusCode: resp.StatusCode}
+	}
+	var rd rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&rd); err != nil {
+		return nil, fmt.Errorf("error parsing rdap record: %v", err)
+	}
+	rec := &WhoisRecord{Source: WhoisSourceRDAP}
+	for _, ev := range rd.Events {
+		t, err := time.Parse(time.RFC3339, ev.EventDate)
+		if err != nil {
+			continue
+		}
+		switch ev.EventAction {
+		case "registration":
+			rec.RegistrationDate = t
+		case "expiration":
+			rec.ExpirationDate = t
+		}
+	}
+	for _, ns := range rd.Nameservers {
+		if ns.LdhName != "" {
+			rec.NameServers = append(rec.NameServers, strings.ToLower(strings.TrimSuffix(ns.LdhName, ".")))
+		}
+	}
+	var walk func([]rdapEntity)
+	walk = func(ents []rdapEntity) {
+		for _, e := range ents {
+			for _, role := range e.Roles {
+				switch role {
+				case "registrar":
+					if rec.Registrar == "" {
+						rec.Registrar = vcardValue(e.VcardArray, "fn")
+					}
+				case "registrant":
+					// fn is often a contact person or role rather than the organization
+					if rec.RegistrantOrg == "" {
+						rec.RegistrantOrg = vcardValue(e.VcardArray, "org")
+					}
+					if rec.RegistrantEmail == "" {
+						rec.RegistrantEmail = strings.ToLower(vcardValue(e.VcardArray, "email"))
+					}
+				}
+			}
+			walk(e.Entities)
+		}
+	}
+	walk(rd.Entities)
+	return rec, nil
+}
+
+// vcardValue returns the first text value of the named property of a jCard (RFC 7095) array
+func vcardValue(vcard []any, name string) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	props, ok := vcard[1].([]any)
+	if !ok {
+		return ""
+	}
+	for _, p := range props {
+		prop, ok := p.([]any)
+		if !ok || len(prop) < 4 {
+			continue
+		}
+		if n, _ := prop[0].(string); n != name {
+			continue
+		}
+		switch v := prop[3].(type) {
+		case string:
+			return strings.TrimSpace(v)
+		case []any:
+			// org may be structured as a list of units
+			for _, u := range v {
+				if s, ok := u.(string); ok && s != "" {
+					return strings.TrimSpace(s)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// maxWhoisReferrals caps the referrals followed from WhoisServer, to the registry and then the registrar
+const maxWhoisReferrals = 2
+
+func whoisExchange(ctx context.Context, server, query string) (string, error) {
+	conn, err := (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	defer watchConn(ctx, conn, 10*time.Second)()
+	if _, err := fmt.Fprintf(conn, "%s\r\n", query); err != nil {
+		return "", err
+	}
+	body, err := io.ReadAll(io.LimitReader(conn, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func queryWhois(ctx context.Context, domainName string) (*WhoisRecord, error) {
+	body, err := whoisExchange(ctx, WhoisServer, domainName)
+	if err != nil {
+		return nil, err
+	}
+	// The IANA server refers to the registry, and thin registries refer on to the registrar
+	fields := parseWhoisFields(body)
+	latest := fields
+	visited := map[string]bool{WhoisServer: true}
+	for i := 0; i < maxWhoisReferrals; i++ {
+		server := whoisReferral(latest)
+		if server == "" || visited[server] {
+			break
+		}
+		visited[server] = true
+		body, err = whoisExchange(ctx, server, domainName)
+		if err != nil && i == 0 {
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("Error following whois referral of %s to %s: %s\n", domainName, server, err)
+			break
+		}
+		// The IANA answer describes the TLD and is replaced, a registrar's fields win over the registry's
+		latest = parseWhoisFields(body)
+		if i == 0 {
+			fields = latest
+			continue
+		}
+		for k, v := range latest {
+			fields[k] = v
+		}
+	}
+	rec := &WhoisRecord{
+		Source:          WhoisSourceWhois,
+		Registrar:       firstField(fields, "registrar", "sponsoring registrar"),
+		RegistrantOrg:   firstField(fields, "registrant organization", "registrant organisation", "registrant"),
+		RegistrantEmail: strings.ToLower(firstField(fields, "registrant email")),
+	}
+	rec.RegistrationDate = parseWhoisDate(firstField(fields, "creation date", "created", "registered on"))
+	rec.ExpirationDate = parseWhoisDate(
+		firstField(
+			fields, "registry expiry date", "registrar registration expiration date", "expiration date", "expiry date",
+			"expires",
+		),
+	)
+	for _, ns := range append(fields["name server"], fields["nserver"]...) {
+		ns = strings.ToLower(strings.TrimSuffix(strings.Fields(ns)[0], "."))
+		rec.NameServers = append(rec.NameServers, ns)
+	}
+	if rec.Registrar == "" && rec.RegistrationDate.IsZero() && len(rec.NameServers) == 0 {
+		return nil, fmt.Errorf("No whois data found for %s", domainName)
+	}
+	return rec, nil
+}
+
+// whoisReferral returns the host:port of the server a whois response refers to, if any
+func whoisReferral(fields map[string][]string) string {
+	server := firstField(fields, "refer", "whois", "registrar whois server")
+	if server == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "43")
+	}
+	return server
+}
+
+// parseWhoisFields collects the "key: value" lines of a whois response keyed by lowercased key
+func parseWhoisFields(body string) map[string][]string {
+	fields := make(map[string][]string)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">>>") {
+			continue
+		}
+		k, v, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		fields[k] = append(fields[k], v)
+	}
+	return fields
+}
+
+func firstField(fields map[string][]string, keys ...string) string {
+	for _, k := range keys {
+		if v := fields[k]; len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func parseWhoisDate(s string) time.Time {
+	layouts := []string{
+		time.RFC3339, "2006-01-02T15:04:05Z", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02",
+		"02-Jan-2006", "2006.01.02", "02.01.2006",
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
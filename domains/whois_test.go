@@ -0,0 +1,260 @@
+package domains
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const rdapResponse = `{
+	"objectClassName": "domain",
+	"ldhName": "example.com",
+	"events": [
+		{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+		{"eventAction": "expiration", "eventDate": "2030-08-13T04:00:00Z"}
+	],
+	"nameservers": [{"ldhName": "A.IANA-SERVERS.NET"}, {"ldhName": "B.IANA-SERVERS.NET."}],
+	"entities": [
+		{
+			"roles": ["registrar"],
+			"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar, Inc."]]],
+			"entities": [
+				{
+					"roles": ["registrant"],
+					"vcardArray": ["vcard", [
+						["version", {}, "text", "4.0"],
+						["fn", {}, "text", "Domain Admin"],
+						["org", {}, "text", "Example Holdings LLC"],
+						["email", {}, "text", "Hostmaster@Example.com"]
+					]]
+				}
+			]
+		}
+	]
+}`
+
+func newRDAPServer(t *testing.T, found bool) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/dns.json":
+					fmt.Fprintf(w, `{"services": [[["com", "net"], ["%s/rdap/"]]]}`, srv.URL)
+				case r.URL.Path == "/rdap/domain/example.com" && found:
+					w.Header().Set("Content-Type", "application/rdap+json")
+					w.Write([]byte(rdapResponse))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newWhoisServer answers port-43 style queries, the first server refers to the second
+func newWhoisServer(t *testing.T, respond func(query string) string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			q, _ := bufio.NewReader(conn).ReadString('\n')
+			conn.Write([]byte(respond(strings.TrimSpace(q))))
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func setWhoisServers(t *testing.T, bootstrap, whois string) {
+	oldBootstrap, oldWhois := RDAPBootstrapURL, WhoisServer
+	RDAPBootstrapURL, WhoisServer = bootstrap, whois
+	t.Cleanup(func() { RDAPBootstrapURL, WhoisServer = oldBootstrap, oldWhois })
+}
+
+func TestGetWhoisDataRDAP(t *testing.T) {
+	srv := newRDAPServer(t, true)
+	setWhoisServers(t, srv.URL+"/dns.json", "127.0.0.1:1")
+	d, err := NewDomain("www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.GetWhoisData(); err != nil {
+		t.Fatal(err)
+	}
+	if d.LastRanWhois.IsZero() {
+		t.Error("LastRanWhois was not set")
+	}
+	w := d.Whois
+	if w.Source != WhoisSourceRDAP {
+		t.Errorf("Source = %q, want %q", w.Source, WhoisSourceRDAP)
+	}
+	if w.Registrar != "Example Registrar, Inc." {
+		t.Errorf("Registrar = %q", w.Registrar)
+	}
+	if w.RegistrantOrg != "Example Holdings LLC" {
+		t.Errorf("RegistrantOrg = %q", w.RegistrantOrg)
+	}
+	if w.RegistrantEmail != "hostmaster@example.com" {
+		t.Errorf("RegistrantEmail = %q", w.RegistrantEmail)
+	}
+	if w.RegistrationDate.Year() != 1995 || w.ExpirationDate.Year() != 2030 {
+		t.Errorf("dates = %v, %v", w.RegistrationDate, w.ExpirationDate)
+	}
+	if strings.Join(w.NameServers, ",") != "a.iana-servers.net,b.iana-servers.net" {
+		t.Errorf("NameServers = %v", w.NameServers)
+	}
+}
+
+func TestQueryRDAPBootstrapRetry(t *testing.T) {
+	bootstraps := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/retry.json":
+					// The first fetch fails, and must not be cached
+					if bootstraps++; bootstraps == 1 {
+						http.Error(w, "unavailable", http.StatusServiceUnavailable)
+						return
+					}
+					fmt.Fprintf(w, `{"services": [[["org"], ["%s/rdap/"]]]}`, srv.URL)
+				case "/rdap/domain/example.org":
+					w.Write(
+						[]byte(`{"entities": [{"roles": ["registrant"], "vcardArray": ["vcard", [
+							["version", {}, "text", "4.0"], ["fn", {}, "text", "Domain Admin"]]]}]}`),
+					)
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	t.Cleanup(srv.Close)
+	setWhoisServers(t, srv.URL+"/retry.json", "127.0.0.1:1")
+
+	if _, err := queryRDAP(context.Background(), srv.Client(), "example.org", "org"); err == nil {
+		t.Fatal("expected an error while the bootstrap is unavailable")
+	}
+	rec, err := queryRDAP(context.Background(), srv.Client(), "example.org", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A registrant's fn names a contact, not the organization
+	if rec.RegistrantOrg != "" {
+		t.Errorf("RegistrantOrg = %q, want none without an org", rec.RegistrantOrg)
+	}
+	if bootstraps != 2 {
+		t.Errorf("bootstrap fetched %d times, want 2", bootstraps)
+	}
+}
+
+func TestGetWhoisDataFallback(t *testing.T) {
+	srv := newRDAPServer(t, false)
+	// The thin registry leaves the registrant to the registrar, whose own referral is past the cap
+	registrar := newWhoisServer(
+		t, func(q string) string {
+			return "Domain Name: " + q + "\r\nRegistrar WHOIS Server: 127.0.0.1:1\r\n" +
+				"Registrant Organization: Example Holdings LLC\r\n"
+		},
+	)
+	registry := newWhoisServer(
+		t, func(q string) string {
+			return fmt.Sprintf(
+				"Domain Name: %s\r\nRegistrar WHOIS Server: %s\r\nRegistrar: Example Registrar, Inc.\r\n"+
+					"Creation Date: 1995-08-14T04:00:00Z\r\nRegistry Expiry Date: 2030-08-13T04:00:00Z\r\n"+
+					"Name Server: A.IANA-SERVERS.NET\r\nName Server: B.IANA-SERVERS.NET\r\n", strings.ToUpper(q), registrar,
+			)
+		},
+	)
+	root := newWhoisServer(
+		t, func(q string) string {
+			return "% IANA WHOIS server\r\n\r\nrefer:        " + registry + "\r\n"
+		},
+	)
+	setWhoisServers(t, srv.URL+"/dns.json", root)
+	d, err := NewDomain("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.GetWhoisData(); err != nil {
+		t.Fatal(err)
+	}
+	w := d.Whois
+	if w.Source != WhoisSourceWhois {
+		t.Errorf("Source = %q, want %q", w.Source, WhoisSourceWhois)
+	}
+	if w.Registrar != "Example Registrar, Inc." || w.RegistrantOrg != "Example Holdings LLC" {
+		t.Errorf("Registrar = %q, RegistrantOrg = %q", w.Registrar, w.RegistrantOrg)
+	}
+	if w.RegistrationDate.Year() != 1995 || w.ExpirationDate.Year() != 2030 {
+		t.Errorf("dates = %v, %v", w.RegistrationDate, w.ExpirationDate)
+	}
+	if len(w.NameServers) != 2 {
+		t.Errorf("NameServers = %v", w.NameServers)
+	}
+}
+
+func TestGetWhoisRegistrantDomains(t *testing.T) {
+	newDom := func(name, org, email string) *Domain {
+		d, err := NewDomain(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Whois = &WhoisRecord{RegistrantOrg: org, RegistrantEmail: email}
+		return d
+	}
+	doms := []*Domain{
+		newDom("acme.com", "Acme  Corp", "hostmaster@acme.com"),
+		newDom("acme-brand.com", "acme corp", ""),
+		newDom("acme-mail.com", "", "hostmaster@acme.com"),
+		newDom("acme-other.com", "REDACTED FOR PRIVACY", "hostmaster@acme.com"),
+		newDom("unrelated.com", "Redacted for Privacy", "privacy@whoisguard.com"),
+		newDom("other.com", "Redacted for Privacy", "privacy@whoisguard.com"),
+	}
+	idx := NewRegistrantIndex(doms)
+	for _, d := range doms {
+		d.GetWhoisRegistrantDomains(idx)
+	}
+	got := doms[0].GetAllMatchedDomains().WhoisRegistrantDomains
+	sort.Strings(got)
+	if strings.Join(got, ",") != "acme-brand.com,acme-mail.com,acme-other.com" {
+		t.Errorf("acme.com registrant domains = %v", got)
+	}
+	if len(doms[4].WhoisRegistrantDomains) != 0 {
+		t.Errorf("redacted registrants were linked: %v", doms[4].WhoisRegistrantDomains)
+	}
+
+	// Organizations merely containing "private" are not redacted
+	pe := []*Domain{newDom("pe.com", "Private Equity Partners", ""), newDom("pe-fund.com", "Private Equity Partners", "")}
+	pe[0].GetWhoisRegistrantDomains(NewRegistrantIndex(pe))
+	if got := pe[0].GetAllMatchedDomains().WhoisRegistrantDomains; len(got) != 1 || got[0] != "pe-fund.com" {
+		t.Errorf("pe.com registrant domains = %v", got)
+	}
+
+	// Registrants on more than MaxRegistrantDomains domains are resellers
+	var reseller []*Domain
+	for i := 0; i <= MaxRegistrantDomains; i++ {
+		reseller = append(reseller, newDom(fmt.Sprintf("reseller%d.com", i), "Reseller Ltd", ""))
+	}
+	reseller[0].GetWhoisRegistrantDomains(NewRegistrantIndex(reseller))
+	if len(reseller[0].WhoisRegistrantDomains) != 0 {
+		t.Errorf("reseller registrant was linked: %v", reseller[0].WhoisRegistrantDomains)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -54,6 +56,8 @@ func NewBQStore(projectID, datasetID, tableName string) (*BQStore, error) {
 		} else {
 			return nil, err
 		}
+	} else if err := updateTableSchema(ctx, table); err != nil {
+		return nil, err
 	}
 	return &BQStore{
 		Mut:     &sync.RWMutex{},
@@ -63,6 +67,66 @@ func NewBQStore(projectID, datasetID, tableName string) (*BQStore, error) {
 	}, nil
 }
 
+// updateTableSchema adds any columns of DomainBQ missing from an existing table, new columns are always nullable
+func updateTableSchema(ctx context.Context, table *bigquery.Table) error {
+	md, err := table.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	schema, err := bigquery.InferSchema(DomainBQ{})
+	if err != nil {
+		return err
+	}
+	merged, changed := mergeSchema(md.Schema, schema)
+	if !changed {
+		return nil
+	}
+	log.Printf("Adding new columns to table %s", table.TableID)
+	_, err = table.Update(ctx, bigquery.TableMetadataToUpdate{Schema: merged}, md.ETag)
+	return err
+}
+
+func mergeSchema(existing, wanted bigquery.Schema) (bigquery.Schema, bool) {
+	changed := false
+	fields := make(map[string]*bigquery.FieldSchema)
+	for _, f := range existing {
+		fields[f.Name] = f
+	}
+	merged := append(bigquery.Schema{}, existing...)
+	for _, w := range wanted {
+		f, ok := fields[w.Name]
+		if !ok {
+			merged = append(merged, relaxField(w))
+			changed = true
+			continue
+		}
+		if f.Type == bigquery.RecordFieldType && w.Type == bigquery.RecordFieldType {
+			if sub, subChanged := mergeSchema(f.Schema, w.Schema); subChanged {
+				nf := *f
+				nf.Schema = sub
+				for i := range merged {
+					if merged[i].Name == f.Name {
+						merged[i] = &nf
+					}
+				}
+				changed = true
+			}
+		}
+	}
+	return merged, changed
+}
+
+// relaxField copies a field making it and its nested fields nullable, as new columns cannot be required
+func relaxField(f *bigquery.FieldSchema) *bigquery.FieldSchema {
+	nf := *f
+	nf.Required = false
+	nf.Schema = nil
+	for _, sub := range f.Schema {
+		nf.Schema = append(nf.Schema, relaxField(sub))
+	}
+	return &nf
+}
+
 func (bq *BQStore) recreateMergeTable(ctx context.Context) error {
 	qry := `create table domwalk.domain_mrg
 				(
@@ -79,16 +143,98 @@ func (bq *BQStore) recreateMergeTable(ctx context.Context) error {
 					last_ran_dns            TIMESTAMP,
 					last_ran_cert_sans      TIMESTAMP,
 					last_ran_sitemap_parse  TIMESTAMP,
-					a_records               ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, ip STRING>>,
-					aaaa_records            ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, ip_v6 STRING>>,
+					a_records               ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, ip STRING,
+															ptr ARRAY <STRING>>>,
+					aaaa_records            ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, ip_v6 STRING,
+															ptr ARRAY <STRING>>>,
 					mx_records              ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, mx STRING>>,
 					soa_records             ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, ns STRING, mbox STRING,
 															serial INT64>>,
 					sitemaps                ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, sitemap_loc STRING>>,
-					web_redirect_domains    ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING>>,
-					cert_sans               ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING>>,
-					sitemap_web_domains     ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING>>,
-					sitemap_contact_domains ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING>>
+					web_redirect_domains    ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															hosts ARRAY <STRING>>>,
+					cert_sans               ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															endpoints ARRAY <STRING>, hosts ARRAY <STRING>>>,
+					sitemap_web_domains     ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															hosts ARRAY <STRING>>>,
+					sitemap_contact_domains ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															hosts ARRAY <STRING>>>,
+					last_ran_whois          TIMESTAMP,
+					whois                   STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, source STRING, registrar STRING,
+													registrant_org STRING, registrant_email STRING,
+													registration_date TIMESTAMP, expiration_date TIMESTAMP,
+													name_servers ARRAY <STRING>>,
+					whois_registrant_domains ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															hosts ARRAY <STRING>>>,
+					ns_records              ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, ns STRING>>,
+					txt_records             ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, text STRING>>,
+					cname_records           ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, host STRING, target STRING>>,
+					caa_records             ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, flag INT64, tag STRING,
+															value STRING>>,
+					srv_records             ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, service STRING,
+															target STRING, port INT64, priority INT64, weight INT64>>,
+					last_ran_spf            TIMESTAMP,
+					spf_include_domains     ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															include STRING, generic BOOL, hosts ARRAY <STRING>>>,
+					last_ran_dmarc          TIMESTAMP,
+					dmarc                   STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, raw STRING, p STRING, sp STRING,
+													pct INT64, rua ARRAY <STRING>, ruf ARRAY <STRING>>,
+					dkim_records            ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, selector STRING,
+															key_type STRING, public_key STRING>>,
+					dmarc_report_domains    ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															mailbox STRING, hosts ARRAY <STRING>>>,
+					shared_ip_domains       ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															ip STRING>>,
+					shared_mx_domains       ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															mx STRING>>,
+					shared_ns_domains       ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															ns STRING>>,
+					mail_provider           STRING,
+					dns_host                STRING,
+					certificate             STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, endpoint STRING, issuer STRING,
+													issuer_organization STRING, subject_common_name STRING,
+													subject_organization STRING, serial STRING, fingerprint_sha256 STRING,
+													not_before TIMESTAMP, not_after TIMESTAMP, key_type STRING,
+													self_signed BOOL, chain_valid BOOL, verify_error STRING,
+													chain ARRAY <STRUCT < subject STRING, issuer STRING,
+																		fingerprint_sha256 STRING, not_before TIMESTAMP,
+																		not_after TIMESTAMP>>>,
+					cert_org_domains        ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															organization STRING>>,
+					last_ran_ct             TIMESTAMP,
+					ct_log_domains          ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															first_seen TIMESTAMP, last_seen TIMESTAMP,
+															hosts ARRAY <STRING>>>,
+					domain_name_unicode     STRING,
+					enrichment_errors       ARRAY <STRUCT < strategy STRING, class STRING, message STRING,
+															occurred_at TIMESTAMP>>,
+					strategy_results        ARRAY <STRUCT < strategy STRING, last_ran TIMESTAMP,
+															domains ARRAY <STRUCT < created_at TIMESTAMP,
+																					updated_at TIMESTAMP,
+																					domain_name STRING,
+																					relationship STRING,
+																					hosts ARRAY <STRING>>>>>,
+					redirect_chain          ARRAY <STRUCT < start STRING, url STRING, method STRING,
+															status_code INT64, type STRING, location STRING,
+															duration_ms INT64>>,
+					web_landing_variant     STRING,
+					last_ran_homepage_links TIMESTAMP,
+					homepage_link_domains   ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															hosts ARRAY <STRING>, sections ARRAY <STRING>,
+															anchor_texts ARRAY <STRING>, generic BOOL>>,
+					last_ran_trackers       TIMESTAMP,
+					tracker_ids             ARRAY <STRUCT < type STRING, id STRING, account STRING, source STRING>>,
+					shared_tracker_domains  ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															tracker STRING, account STRING>>,
+					organization            STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, display_name STRING,
+													name STRING, legal_name STRING, url STRING, same_as ARRAY <STRING>,
+													parent_organizations ARRAY <STRUCT < name STRING, url STRING>>,
+													sub_organizations ARRAY <STRUCT < name STRING, url STRING>>,
+													site_name STRING, title STRING>,
+					last_ran_structured_data TIMESTAMP,
+					structured_data_domains ARRAY <STRUCT < created_at TIMESTAMP, updated_at TIMESTAMP, domain_name STRING,
+															hosts ARRAY <STRING>, relationships ARRAY <STRING>,
+															generic BOOL>>
 				);`
 	_, err := bq.Client.Query(qry).Read(ctx)
 	return err
@@ -122,7 +268,45 @@ func (bq *BQStore) PutDomains(ctx context.Context, doms []*domains.Domain) error
 									t.web_redirect_domains = s.web_redirect_domains,
 									t.cert_sans = s.cert_sans,
 									t.sitemap_web_domains = s.sitemap_web_domains,
-									t.sitemap_contact_domains = s.sitemap_contact_domains
+									t.sitemap_contact_domains = s.sitemap_contact_domains,
+									t.last_ran_whois = GREATEST(IFNULL(t.last_ran_whois, s.last_ran_whois), IFNULL(s.last_ran_whois, t.last_ran_whois)),
+									t.whois = s.whois,
+									t.whois_registrant_domains = s.whois_registrant_domains,
+									t.ns_records = s.ns_records,
+									t.txt_records = s.txt_records,
+									t.cname_records = s.cname_records,
+									t.caa_records = s.caa_records,
+									t.srv_records = s.srv_records,
+									t.last_ran_spf = GREATEST(IFNULL(t.last_ran_spf, s.last_ran_spf), IFNULL(s.last_ran_spf, t.last_ran_spf)),
+									t.spf_include_domains = s.spf_include_domains,
+									t.last_ran_dmarc = GREATEST(IFNULL(t.last_ran_dmarc, s.last_ran_dmarc), IFNULL(s.last_ran_dmarc, t.last_ran_dmarc)),
+									t.dmarc = s.dmarc,
+									t.dkim_records = s.dkim_records,
+									t.dmarc_report_domains = s.dmarc_report_domains,
+									t.shared_ip_domains = s.shared_ip_domains,
+									t.shared_mx_domains = s.shared_mx_domains,
+									t.shared_ns_domains = s.shared_ns_domains,
+									t.mail_provider = IFNULL(s.mail_provider, t.mail_provider),
+									t.dns_host = IFNULL(s.dns_host, t.dns_host),
+									t.certificate = IFNULL(s.certificate, t.certificate),
+									t.cert_org_domains = s.cert_org_domains,
+									t.last_ran_ct = GREATEST(IFNULL(t.last_ran_ct, s.last_ran_ct), IFNULL(s.last_ran_ct, t.last_ran_ct)),
+									t.ct_log_domains = s.ct_log_domains,
+									t.domain_name_unicode = IFNULL(s.domain_name_unicode, t.domain_name_unicode),
+									t.enrichment_errors = s.enrichment_errors,
+									t.strategy_results = s.strategy_results,
+									t.redirect_chain = s.redirect_chain,
+									t.successful_web_landing = s.successful_web_landing,
+									t.web_redirect_url_final = IFNULL(s.web_redirect_url_final, t.web_redirect_url_final),
+									t.web_landing_variant = s.web_landing_variant,
+									t.last_ran_homepage_links = GREATEST(IFNULL(t.last_ran_homepage_links, s.last_ran_homepage_links), IFNULL(s.last_ran_homepage_links, t.last_ran_homepage_links)),
+									t.homepage_link_domains = s.homepage_link_domains,
+									t.last_ran_trackers = GREATEST(IFNULL(t.last_ran_trackers, s.last_ran_trackers), IFNULL(s.last_ran_trackers, t.last_ran_trackers)),
+									t.tracker_ids = s.tracker_ids,
+									t.shared_tracker_domains = s.shared_tracker_domains,
+									t.organization = s.organization,
+									t.last_ran_structured_data = GREATEST(IFNULL(t.last_ran_structured_data, s.last_ran_structured_data), IFNULL(s.last_ran_structured_data, t.last_ran_structured_data)),
+									t.structured_data_domains = s.structured_data_domains
 					WHEN NOT MATCHED THEN INSERT ROW;`,
 			bq.Dataset.DatasetID, bq.Table.TableID,
 		),
@@ -159,9 +343,51 @@ func (bq *BQStore) GetDomains(ctx context.Context, query string) ([]*domains.Dom
 	return doms, nil
 }
 
+// GetDomainsByQuery runs a parameterized query against the domains table
+func (bq *BQStore) GetDomainsByQuery(
+	ctx context.Context, query string, params []bigquery.QueryParameter,
+) ([]*domains.Domain, error) {
+	var doms []*domains.Domain
+	bq.Mut.RLock()
+	defer bq.Mut.RUnlock()
+	q := bq.Client.Query(query)
+	q.Parameters = params
+	it, err := q.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var d DomainBQ
+		err := it.Next(&d)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		doms = append(doms, d.parse())
+	}
+	return doms, nil
+}
+
+// GetDomainsByNames canonicalizes the names to their registrable ASCII domains, returning the stored domains
+// and new domains for the rest
 func (bq *BQStore) GetDomainsByNames(ctx context.Context, doms []string) ([]*domains.Domain, error) {
 	var domObjs []*domains.Domain
 	var domsFound = make(map[string]bool)
+	var names []string
+	parsed := make(map[string]*domains.Domain)
+	for _, dom := range doms {
+		d, err := domains.NewDomain(dom)
+		if err != nil {
+			log.Printf("Error parsing domain %s: %s\n", dom, err)
+			continue
+		}
+		if _, exists := parsed[d.DomainName]; !exists {
+			parsed[d.DomainName] = d
+			names = append(names, d.DomainName)
+		}
+	}
 	bq.Mut.RLock()
 	qry := bq.Client.Query(
 		`SELECT * FROM ` + fmt.Sprintf(
@@ -169,7 +395,7 @@ func (bq *BQStore) GetDomainsByNames(ctx context.Context, doms []string) ([]*dom
 		) + ` WHERE domain_name IN UNNEST(@dns)`,
 	)
 	qry.Parameters = []bigquery.QueryParameter{
-		{Name: "dns", Value: doms},
+		{Name: "dns", Value: names},
 	}
 	it, err := qry.Read(ctx)
 	if err != nil {
@@ -188,16 +414,178 @@ func (bq *BQStore) GetDomainsByNames(ctx context.Context, doms []string) ([]*dom
 		domsFound[d.DomainName] = true
 	}
 	bq.Mut.RUnlock()
-	for _, dom := range doms {
-		if _, exists := domsFound[dom]; !exists {
-			domsFound[dom] = true
-			d, err := domains.NewDomain(dom)
-			if err != nil {
-				log.Printf("Error parsing domain %s: %s\n", dom, err)
-				continue
-			}
-			domObjs = append(domObjs, d)
+	for _, dn := range names {
+		if !domsFound[dn] {
+			domObjs = append(domObjs, parsed[dn])
 		}
 	}
 	return domObjs, nil
 }
+
+// GetRegistrantIndex indexes the given domains together with every stored domain sharing one of their registrants,
+// at most MaxRegistrantDomains+1 per registrant
+func (bq *BQStore) GetRegistrantIndex(ctx context.Context, doms []*domains.Domain) (domains.DomainIndex, error) {
+	var keys []string
+	for _, d := range doms {
+		keys = append(keys, d.Whois.RegistrantKeys()...)
+	}
+	if len(keys) == 0 {
+		return domains.NewRegistrantIndex(doms), nil
+	}
+	stored, err := bq.GetDomainsByQuery(
+		ctx, fmt.Sprintf(
+			`SELECT domain_name, whois FROM (
+					SELECT domain_name, whois,
+						CONCAT('org:', LOWER(REGEXP_REPLACE(TRIM(whois.registrant_org), r'\s+', ' '))) AS registrant_key
+					FROM %[1]s.%[2]s
+					UNION ALL
+					SELECT domain_name, whois, CONCAT('email:', LOWER(TRIM(whois.registrant_email)))
+					FROM %[1]s.%[2]s
+				)
+				WHERE registrant_key IN UNNEST(@keys)
+				QUALIFY ROW_NUMBER() OVER (PARTITION BY registrant_key) <= @limit`,
+			bq.Dataset.DatasetID, bq.Table.TableID,
+		), []bigquery.QueryParameter{
+			{Name: "keys", Value: keys}, {Name: "limit", Value: domains.MaxRegistrantDomains + 1},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return domains.NewRegistrantIndex(append(stored, doms...)), nil
+}
+
+// GetSharedIPIndex indexes the given domains together with every stored domain resolving to one of their
+// dedicated addresses
+func (bq *BQStore) GetSharedIPIndex(
+	ctx context.Context, doms []*domains.Domain, l *domains.SharedHostDenylist,
+) (domains.DomainIndex, error) {
+	var ips []string
+	for _, d := range doms {
+		ips = append(ips, d.DedicatedIPs(l)...)
+	}
+	if len(ips) == 0 {
+		return domains.NewSharedIPIndex(doms, l), nil
+	}
+	stored, err := bq.GetDomainsByQuery(
+		ctx, fmt.Sprintf(
+			`SELECT * FROM %s.%s
+				WHERE EXISTS(SELECT 1 FROM UNNEST(a_records) a WHERE a.ip IN UNNEST(@ips))
+				   OR EXISTS(SELECT 1 FROM UNNEST(aaaa_records) a WHERE a.ip_v6 IN UNNEST(@ips))`,
+			bq.Dataset.DatasetID, bq.Table.TableID,
+		), []bigquery.QueryParameter{{Name: "ips", Value: ips}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return domains.NewSharedIPIndex(append(stored, doms...), l), nil
+}
+
+// GetSharedInfraIndex indexes the given domains together with every stored domain using one of their
+// self-hosted mail exchangers or vanity nameservers
+func (bq *BQStore) GetSharedInfraIndex(ctx context.Context, doms []*domains.Domain) (domains.DomainIndex, error) {
+	var mxs, nss []string
+	for _, d := range doms {
+		mxs = append(mxs, d.SelfHostedMX()...)
+		nss = append(nss, d.VanityNS()...)
+	}
+	if len(mxs) == 0 && len(nss) == 0 {
+		return domains.NewSharedInfraIndex(doms), nil
+	}
+	stored, err := bq.GetDomainsByQuery(
+		ctx, fmt.Sprintf(
+			`SELECT * FROM %s.%s
+				WHERE EXISTS(SELECT 1 FROM UNNEST(mx_records) m WHERE LOWER(RTRIM(m.mx, '.')) IN UNNEST(@mxs))
+				   OR EXISTS(SELECT 1 FROM UNNEST(ns_records) n WHERE LOWER(RTRIM(n.ns, '.')) IN UNNEST(@nss))`,
+			bq.Dataset.DatasetID, bq.Table.TableID,
+		), []bigquery.QueryParameter{{Name: "mxs", Value: mxs}, {Name: "nss", Value: nss}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return domains.NewSharedInfraIndex(append(stored, doms...)), nil
+}
+
+// GetSharedTrackerIndex indexes the given domains together with every stored domain sharing one of their tracker
+// accounts
+func (bq *BQStore) GetSharedTrackerIndex(ctx context.Context, doms []*domains.Domain) (domains.DomainIndex, error) {
+	var accounts []string
+	for _, d := range doms {
+		accounts = append(accounts, d.TrackerAccounts()...)
+	}
+	if len(accounts) == 0 {
+		return domains.NewSharedTrackerIndex(doms), nil
+	}
+	stored, err := bq.GetDomainsByQuery(
+		ctx, fmt.Sprintf(
+			`SELECT * FROM %s.%s
+				WHERE EXISTS(SELECT 1 FROM UNNEST(tracker_ids) x WHERE CONCAT(x.type, ':', x.account) IN UNNEST(@accounts))`,
+			bq.Dataset.DatasetID, bq.Table.TableID,
+		), []bigquery.QueryParameter{{Name: "accounts", Value: accounts}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return domains.NewSharedTrackerIndex(append(stored, doms...)), nil
+}
+
+// orgSuffixPattern matches the trailing legal entity designations Normalize strips, in OrgKey form
+func orgSuffixPattern(n domains.OrgNormalization) string {
+	suffixes := n.Suffixes
+	if len(suffixes) == 0 {
+		suffixes = domains.OrgSuffixes
+	}
+	var alts []string
+	for _, s := range suffixes {
+		if k := domains.OrgKey(s); k != "" {
+			alts = append(alts, regexp.QuoteMeta(k))
+		}
+	}
+	return `(?:\s+(?:` + strings.Join(alts, "|") + `))+$`
+}
+
+// GetCertOrgIndex indexes the given domains together with every stored domain whose certificate names one of
+// their organizations. Stored organizations are reduced to their OrgKey with suffixes stripped and matched on
+// equality, at most MaxCertOrgDomains+1 per organization, then matched exactly once normalized
+func (bq *BQStore) GetCertOrgIndex(
+	ctx context.Context, doms []*domains.Domain, n domains.OrgNormalization,
+) (domains.DomainIndex, error) {
+	var keys []string
+	for _, d := range doms {
+		if org := d.CertOrganization(n); org != "" {
+			keys = append(keys, domains.OrgKey(org))
+		}
+	}
+	if len(keys) == 0 {
+		return domains.NewCertOrgIndex(doms, n), nil
+	}
+	stored, err := bq.GetDomainsByQuery(
+		ctx, fmt.Sprintf(
+			`SELECT domain_name, STRUCT(
+					subject_organization AS subject_organization, TRUE AS chain_valid, FALSE AS self_signed
+				) AS certificate
+				FROM (
+					SELECT domain_name, certificate.subject_organization, REGEXP_REPLACE(
+						REGEXP_REPLACE(
+							TRIM(REGEXP_REPLACE(
+								LOWER(REGEXP_REPLACE(certificate.subject_organization, r"[.']", '')),
+								r'[^\p{L}\p{N}]+', ' '
+							)), @suffixes, ''
+						), r'[^\p{L}\p{N}]', ''
+					) AS org_key
+					FROM %s.%s
+					WHERE certificate.chain_valid AND NOT certificate.self_signed
+				)
+				WHERE org_key IN UNNEST(@keys)
+				QUALIFY ROW_NUMBER() OVER (PARTITION BY org_key) <= @limit`,
+			bq.Dataset.DatasetID, bq.Table.TableID,
+		), []bigquery.QueryParameter{
+			{Name: "keys", Value: keys}, {Name: "suffixes", Value: orgSuffixPattern(n)},
+			{Name: "limit", Value: domains.MaxCertOrgDomains + 1},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return domains.NewCertOrgIndex(append(stored, doms...), n), nil
+}
@@ -3,6 +3,8 @@ package bq
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,3 +38,25 @@ func TestDomains(t *testing.T) {
 	}
 	fmt.Println(doms[0].GetAllMatchedDomains())
 }
+
+// sqlOrgKey mirrors the org_key expression of GetCertOrgIndex
+func sqlOrgKey(org string, n domains.OrgNormalization) string {
+	s := regexp.MustCompile(`[.']`).ReplaceAllString(org, "")
+	s = strings.TrimSpace(regexp.MustCompile(`[^\p{L}\p{N}]+`).ReplaceAllString(strings.ToLower(s), " "))
+	s = regexp.MustCompile(orgSuffixPattern(n)).ReplaceAllString(s, "")
+	return regexp.MustCompile(`[^\p{L}\p{N}]`).ReplaceAllString(s, "")
+}
+
+func TestOrgSuffixPattern(t *testing.T) {
+	orgs := []string{
+		"Acme, Inc.", "ACME Holdings L.L.C.", "Co Inc", "O'Brien & Sons Pty Ltd", "Société Générale S.A.", "Inc",
+	}
+	for _, n := range []domains.OrgNormalization{{}, {Suffixes: []string{"holdings", "inc"}}} {
+		for _, org := range orgs {
+			want := domains.OrgKey(n.Normalize(org))
+			if got := sqlOrgKey(org, n); got != want {
+				t.Errorf("%q with suffixes %v: got %q, want %q", org, n.Suffixes, got, want)
+			}
+		}
+	}
+}
@@ -8,28 +8,65 @@ import (
 )
 
 type DomainBQ struct {
-	CreatedAt             time.Time           `bigquery:"created_at"`
-	UpdatedAt             time.Time           `bigquery:"updated_at"`
-	DomainName            string              `bigquery:"domain_name"`
-	NonPublicDomain       bool                `bigquery:"non_public_domain"`
-	Hostname              bigquery.NullString `bigquery:"hostname"`
-	Subdomain             bigquery.NullString `bigquery:"subdomain"`
-	Suffix                bigquery.NullString `bigquery:"suffix"`
-	SuccessfulWebLanding  bool                `bigquery:"successful_web_landing"`
-	WebRedirectURLFinal   bigquery.NullString `bigquery:"web_redirect_url_final"`
-	LastRanWebRedirect    time.Time           `bigquery:"last_ran_web_redirect"`
-	LastRanDns            time.Time           `bigquery:"last_ran_dns"`
-	LastRanCertSans       time.Time           `bigquery:"last_ran_cert_sans"`
-	LastRanSitemapParse   time.Time           `bigquery:"last_ran_sitemap_parse"`
-	ARecords              []ARecordBQ         `bigquery:"a_records"`
-	AAAARecords           []AAAARecordBQ      `bigquery:"aaaa_records"`
-	MXRecords             []MXRecordBQ        `bigquery:"mx_records"`
-	SOARecords            []SOARecordBQ       `bigquery:"soa_records"`
-	Sitemaps              []SitemapBQ         `bigquery:"sitemaps"`
-	WebRedirectDomains    []MatchedDomainBQ   `bigquery:"web_redirect_domains"`
-	CertSANs              []MatchedDomainBQ   `bigquery:"cert_sans"`
-	SitemapWebDomains     []MatchedDomainBQ   `bigquery:"sitemap_web_domains"`
-	SitemapContactDomains []MatchedDomainBQ   `bigquery:"sitemap_contact_domains"`
+	CreatedAt              time.Time              `bigquery:"created_at"`
+	UpdatedAt              time.Time              `bigquery:"updated_at"`
+	DomainName             string                 `bigquery:"domain_name"`
+	NonPublicDomain        bool                   `bigquery:"non_public_domain"`
+	Hostname               bigquery.NullString    `bigquery:"hostname"`
+	Subdomain              bigquery.NullString    `bigquery:"subdomain"`
+	Suffix                 bigquery.NullString    `bigquery:"suffix"`
+	SuccessfulWebLanding   bool                   `bigquery:"successful_web_landing"`
+	WebRedirectURLFinal    bigquery.NullString    `bigquery:"web_redirect_url_final"`
+	LastRanWebRedirect     time.Time              `bigquery:"last_ran_web_redirect"`
+	LastRanDns             time.Time              `bigquery:"last_ran_dns"`
+	LastRanCertSans        time.Time              `bigquery:"last_ran_cert_sans"`
+	LastRanSitemapParse    time.Time              `bigquery:"last_ran_sitemap_parse"`
+	ARecords               []ARecordBQ            `bigquery:"a_records"`
+	AAAARecords            []AAAARecordBQ         `bigquery:"aaaa_records"`
+	MXRecords              []MXRecordBQ           `bigquery:"mx_records"`
+	SOARecords             []SOARecordBQ          `bigquery:"soa_records"`
+	Sitemaps               []SitemapBQ            `bigquery:"sitemaps"`
+	WebRedirectDomains     []MatchedDomainBQ      `bigquery:"web_redirect_domains"`
+	CertSANs               []CertSansDomainBQ     `bigquery:"cert_sans"`
+	SitemapWebDomains      []MatchedDomainBQ      `bigquery:"sitemap_web_domains"`
+	SitemapContactDomains  []MatchedDomainBQ      `bigquery:"sitemap_contact_domains"`
+	LastRanWhois           bigquery.NullTimestamp `bigquery:"last_ran_whois"`
+	Whois                  *WhoisRecordBQ         `bigquery:"whois"`
+	WhoisRegistrantDomains []MatchedDomainBQ      `bigquery:"whois_registrant_domains"`
+	NSRecords              []NSRecordBQ           `bigquery:"ns_records"`
+	TXTRecords             []TXTRecordBQ          `bigquery:"txt_records"`
+	CNAMERecords           []CNAMERecordBQ        `bigquery:"cname_records"`
+	CAARecords             []CAARecordBQ          `bigquery:"caa_records"`
+	SRVRecords             []SRVRecordBQ          `bigquery:"srv_records"`
+	LastRanSpf             bigquery.NullTimestamp `bigquery:"last_ran_spf"`
+	SpfIncludeDomains      []SpfIncludeDomainBQ   `bigquery:"spf_include_domains"`
+	LastRanDmarc           bigquery.NullTimestamp `bigquery:"last_ran_dmarc"`
+	Dmarc                  *DmarcRecordBQ         `bigquery:"dmarc"`
+	DKIMRecords            []DKIMRecordBQ         `bigquery:"dkim_records"`
+	DmarcReportDomains     []DmarcReportDomainBQ  `bigquery:"dmarc_report_domains"`
+	SharedIPDomains        []SharedIPDomainBQ     `bigquery:"shared_ip_domains"`
+	SharedMXDomains        []SharedMXDomainBQ     `bigquery:"shared_mx_domains"`
+	SharedNSDomains        []SharedNSDomainBQ     `bigquery:"shared_ns_domains"`
+	MailProvider           bigquery.NullString    `bigquery:"mail_provider"`
+	DNSHost                bigquery.NullString    `bigquery:"dns_host"`
+	Certificate            *CertificateBQ         `bigquery:"certificate"`
+	CertOrgDomains         []CertOrgDomainBQ      `bigquery:"cert_org_domains"`
+	LastRanCT              bigquery.NullTimestamp `bigquery:"last_ran_ct"`
+	CTLogDomains           []CTLogDomainBQ        `bigquery:"ct_log_domains"`
+	DomainNameUnicode      bigquery.NullString    `bigquery:"domain_name_unicode"`
+	EnrichmentErrors       []EnrichmentErrorBQ    `bigquery:"enrichment_errors"`
+	StrategyResults        []StrategyResultBQ     `bigquery:"strategy_results"`
+	RedirectChain          []RedirectHopBQ        `bigquery:"redirect_chain"`
+	WebLandingVariant      bigquery.NullString    `bigquery:"web_landing_variant"`
+	LastRanHomepageLinks   bigquery.NullTimestamp `bigquery:"last_ran_homepage_links"`
+	HomepageLinkDomains    []HomepageLinkDomainBQ `bigquery:"homepage_link_domains"`
+	LastRanTrackers        bigquery.NullTimestamp `bigquery:"last_ran_trackers"`
+	TrackerIDs             []TrackerIDBQ          `bigquery:"tracker_ids"`
+	// SharedTrackerDomains are linked across stored domains, see BQStore.GetSharedTrackerIndex
+	SharedTrackerDomains  []SharedTrackerDomainBQ  `bigquery:"shared_tracker_domains"`
+	Organization          *OrganizationBQ          `bigquery:"organization"`
+	LastRanStructuredData bigquery.NullTimestamp   `bigquery:"last_ran_structured_data"`
+	StructuredDataDomains []StructuredDataDomainBQ `bigquery:"structured_data_domains"`
 }
 
 func newDomainBQ(record *domains.Domain) DomainBQ {
@@ -46,6 +83,9 @@ func newDomainBQ(record *domains.Domain) DomainBQ {
 		LastRanDns:           record.LastRanDns,
 		LastRanCertSans:      record.LastRanCertSans,
 		LastRanSitemapParse:  record.LastRanSitemapParse,
+		DomainNameUnicode: bigquery.NullString{
+			Valid: record.DomainNameUnicode != "", StringVal: record.DomainNameUnicode,
+		},
 	}
 	var aRecords []ARecordBQ
 	for _, a := range record.ARecords {
@@ -84,9 +124,9 @@ func newDomainBQ(record *domains.Domain) DomainBQ {
 	}
 	dbq.WebRedirectDomains = webRedirectDomains
 
-	var certSANs []MatchedDomainBQ
+	var certSANs []CertSansDomainBQ
 	for _, a := range record.CertSANs {
-		certSANs = append(certSANs, newMatchedDomainBQ(a.MatchedDomain))
+		certSANs = append(certSANs, newCertSansDomainBQ(a))
 	}
 	dbq.CertSANs = certSANs
 
@@ -102,6 +142,171 @@ func newDomainBQ(record *domains.Domain) DomainBQ {
 	}
 	dbq.SitemapContactDomains = sitemapContactDomains
 
+	dbq.LastRanWhois = bigquery.NullTimestamp{Timestamp: record.LastRanWhois, Valid: !record.LastRanWhois.IsZero()}
+	if record.Whois != nil {
+		w := newWhoisRecordBQ(*record.Whois)
+		dbq.Whois = &w
+	}
+
+	var whoisRegistrantDomains []MatchedDomainBQ
+	for _, a := range record.WhoisRegistrantDomains {
+		whoisRegistrantDomains = append(whoisRegistrantDomains, newMatchedDomainBQ(a.MatchedDomain))
+	}
+	dbq.WhoisRegistrantDomains = whoisRegistrantDomains
+
+	var nsRecords []NSRecordBQ
+	for _, a := range record.NSRecords {
+		nsRecords = append(nsRecords, newNSRecordBQ(a))
+	}
+	dbq.NSRecords = nsRecords
+
+	var txtRecords []TXTRecordBQ
+	for _, a := range record.TXTRecords {
+		txtRecords = append(txtRecords, newTXTRecordBQ(a))
+	}
+	dbq.TXTRecords = txtRecords
+
+	var cnameRecords []CNAMERecordBQ
+	for _, a := range record.CNAMERecords {
+		cnameRecords = append(cnameRecords, newCNAMERecordBQ(a))
+	}
+	dbq.CNAMERecords = cnameRecords
+
+	var caaRecords []CAARecordBQ
+	for _, a := range record.CAARecords {
+		caaRecords = append(caaRecords, newCAARecordBQ(a))
+	}
+	dbq.CAARecords = caaRecords
+
+	var srvRecords []SRVRecordBQ
+	for _, a := range record.SRVRecords {
+		srvRecords = append(srvRecords, newSRVRecordBQ(a))
+	}
+	dbq.SRVRecords = srvRecords
+
+	dbq.LastRanSpf = bigquery.NullTimestamp{Timestamp: record.LastRanSpf, Valid: !record.LastRanSpf.IsZero()}
+	var spfIncludeDomains []SpfIncludeDomainBQ
+	for _, a := range record.SpfIncludeDomains {
+		spfIncludeDomains = append(spfIncludeDomains, newSpfIncludeDomainBQ(a))
+	}
+	dbq.SpfIncludeDomains = spfIncludeDomains
+
+	dbq.LastRanDmarc = bigquery.NullTimestamp{Timestamp: record.LastRanDmarc, Valid: !record.LastRanDmarc.IsZero()}
+	if record.Dmarc != nil {
+		dm := newDmarcRecordBQ(*record.Dmarc)
+		dbq.Dmarc = &dm
+	}
+
+	var dkimRecords []DKIMRecordBQ
+	for _, a := range record.DKIMRecords {
+		dkimRecords = append(dkimRecords, newDKIMRecordBQ(a))
+	}
+	dbq.DKIMRecords = dkimRecords
+
+	var dmarcReportDomains []DmarcReportDomainBQ
+	for _, a := range record.DmarcReportDomains {
+		dmarcReportDomains = append(dmarcReportDomains, newDmarcReportDomainBQ(a))
+	}
+	dbq.DmarcReportDomains = dmarcReportDomains
+
+	var sharedIPDomains []SharedIPDomainBQ
+	for _, a := range record.SharedIPDomains {
+		sharedIPDomains = append(sharedIPDomains, newSharedIPDomainBQ(a))
+	}
+	dbq.SharedIPDomains = sharedIPDomains
+
+	var sharedMXDomains []SharedMXDomainBQ
+	for _, a := range record.SharedMXDomains {
+		sharedMXDomains = append(sharedMXDomains, newSharedMXDomainBQ(a))
+	}
+	dbq.SharedMXDomains = sharedMXDomains
+
+	var sharedNSDomains []SharedNSDomainBQ
+	for _, a := range record.SharedNSDomains {
+		sharedNSDomains = append(sharedNSDomains, newSharedNSDomainBQ(a))
+	}
+	dbq.SharedNSDomains = sharedNSDomains
+
+	dbq.MailProvider = bigquery.NullString{StringVal: record.MailProvider, Valid: record.MailProvider != ""}
+	dbq.DNSHost = bigquery.NullString{StringVal: record.DNSHost, Valid: record.DNSHost != ""}
+
+	if record.Certificate != nil {
+		c := newCertificateBQ(*record.Certificate)
+		dbq.Certificate = &c
+	}
+
+	var certOrgDomains []CertOrgDomainBQ
+	for _, a := range record.CertOrgDomains {
+		certOrgDomains = append(certOrgDomains, newCertOrgDomainBQ(a))
+	}
+	dbq.CertOrgDomains = certOrgDomains
+
+	dbq.LastRanCT = bigquery.NullTimestamp{Timestamp: record.LastRanCT, Valid: !record.LastRanCT.IsZero()}
+	var ctLogDomains []CTLogDomainBQ
+	for _, a := range record.CTLogDomains {
+		ctLogDomains = append(ctLogDomains, newCTLogDomainBQ(a))
+	}
+	dbq.CTLogDomains = ctLogDomains
+
+	var enrichmentErrors []EnrichmentErrorBQ
+	for _, a := range record.EnrichmentErrors {
+		enrichmentErrors = append(enrichmentErrors, newEnrichmentErrorBQ(a))
+	}
+	dbq.EnrichmentErrors = enrichmentErrors
+
+	var strategyResults []StrategyResultBQ
+	for _, a := range record.StrategyResults {
+		strategyResults = append(strategyResults, newStrategyResultBQ(*a))
+	}
+	dbq.StrategyResults = strategyResults
+
+	var redirectChain []RedirectHopBQ
+	for _, a := range record.RedirectChain {
+		redirectChain = append(redirectChain, newRedirectHopBQ(a))
+	}
+	dbq.RedirectChain = redirectChain
+	dbq.WebLandingVariant = bigquery.NullString{
+		StringVal: record.WebLandingVariant, Valid: record.WebLandingVariant != "",
+	}
+	dbq.WebRedirectURLFinal = bigquery.NullString{
+		StringVal: record.WebRedirectURLFinal, Valid: record.WebRedirectURLFinal != "",
+	}
+
+	dbq.LastRanHomepageLinks = bigquery.NullTimestamp{
+		Timestamp: record.LastRanHomepageLinks, Valid: !record.LastRanHomepageLinks.IsZero(),
+	}
+	var homepageLinkDomains []HomepageLinkDomainBQ
+	for _, a := range record.HomepageLinkDomains {
+		homepageLinkDomains = append(homepageLinkDomains, newHomepageLinkDomainBQ(a))
+	}
+	dbq.HomepageLinkDomains = homepageLinkDomains
+
+	dbq.LastRanTrackers = bigquery.NullTimestamp{Timestamp: record.LastRanTrackers, Valid: !record.LastRanTrackers.IsZero()}
+	var trackerIDs []TrackerIDBQ
+	for _, a := range record.TrackerIDs {
+		trackerIDs = append(trackerIDs, newTrackerIDBQ(a))
+	}
+	dbq.TrackerIDs = trackerIDs
+
+	var sharedTrackerDomains []SharedTrackerDomainBQ
+	for _, a := range record.SharedTrackerDomains {
+		sharedTrackerDomains = append(sharedTrackerDomains, newSharedTrackerDomainBQ(a))
+	}
+	dbq.SharedTrackerDomains = sharedTrackerDomains
+
+	if record.Organization != nil {
+		o := newOrganizationBQ(*record.Organization)
+		dbq.Organization = &o
+	}
+	dbq.LastRanStructuredData = bigquery.NullTimestamp{
+		Timestamp: record.LastRanStructuredData, Valid: !record.LastRanStructuredData.IsZero(),
+	}
+	var structuredDataDomains []StructuredDataDomainBQ
+	for _, a := range record.StructuredDataDomains {
+		structuredDataDomains = append(structuredDataDomains, newStructuredDataDomainBQ(a))
+	}
+	dbq.StructuredDataDomains = structuredDataDomains
+
 	return dbq
 }
 
@@ -115,10 +320,16 @@ func (a *DomainBQ) parse() *domains.Domain {
 		Subdomain:            a.Subdomain.StringVal,
 		Suffix:               a.Suffix.StringVal,
 		SuccessfulWebLanding: a.SuccessfulWebLanding,
+		WebRedirectURLFinal:  a.WebRedirectURLFinal.StringVal,
 		LastRanWebRedirect:   a.LastRanWebRedirect,
 		LastRanDns:           a.LastRanDns,
 		LastRanCertSans:      a.LastRanCertSans,
 		LastRanSitemapParse:  a.LastRanSitemapParse,
+		DomainNameUnicode:    a.DomainNameUnicode.StringVal,
+	}
+	// Rows stored before the Unicode form was recorded
+	if d.DomainNameUnicode == "" {
+		d.DomainNameUnicode = domains.UnicodeHost(d.DomainName)
 	}
 	var aRecords []domains.ARecord
 	for _, a := range a.ARecords {
@@ -158,7 +369,7 @@ func (a *DomainBQ) parse() *domains.Domain {
 
 	var certSANs []domains.CertSansDomain
 	for _, a := range a.CertSANs {
-		certSANs = append(certSANs, domains.CertSansDomain{MatchedDomain: a.parse()})
+		certSANs = append(certSANs, a.parse())
 	}
 	d.CertSANs = certSANs
 
@@ -174,6 +385,158 @@ func (a *DomainBQ) parse() *domains.Domain {
 	}
 	d.SitemapContactDomains = sitemapContactDomains
 
+	d.LastRanWhois = a.LastRanWhois.Timestamp
+	if a.Whois != nil {
+		d.Whois = a.Whois.parse()
+	}
+
+	var whoisRegistrantDomains []domains.WhoisRegistrantDomain
+	for _, a := range a.WhoisRegistrantDomains {
+		whoisRegistrantDomains = append(whoisRegistrantDomains, domains.WhoisRegistrantDomain{MatchedDomain: a.parse()})
+	}
+	d.WhoisRegistrantDomains = whoisRegistrantDomains
+
+	var nsRecords []domains.NSRecord
+	for _, a := range a.NSRecords {
+		nsRecords = append(nsRecords, a.parse())
+	}
+	d.NSRecords = nsRecords
+
+	var txtRecords []domains.TXTRecord
+	for _, a := range a.TXTRecords {
+		txtRecords = append(txtRecords, a.parse())
+	}
+	d.TXTRecords = txtRecords
+
+	var cnameRecords []domains.CNAMERecord
+	for _, a := range a.CNAMERecords {
+		cnameRecords = append(cnameRecords, a.parse())
+	}
+	d.CNAMERecords = cnameRecords
+
+	var caaRecords []domains.CAARecord
+	for _, a := range a.CAARecords {
+		caaRecords = append(caaRecords, a.parse())
+	}
+	d.CAARecords = caaRecords
+
+	var srvRecords []domains.SRVRecord
+	for _, a := range a.SRVRecords {
+		srvRecords = append(srvRecords, a.parse())
+	}
+	d.SRVRecords = srvRecords
+
+	d.LastRanSpf = a.LastRanSpf.Timestamp
+	var spfIncludeDomains []domains.SpfIncludeDomain
+	for _, a := range a.SpfIncludeDomains {
+		spfIncludeDomains = append(spfIncludeDomains, a.parse())
+	}
+	d.SpfIncludeDomains = spfIncludeDomains
+
+	d.LastRanDmarc = a.LastRanDmarc.Timestamp
+	if a.Dmarc != nil {
+		d.Dmarc = a.Dmarc.parse()
+	}
+
+	var dkimRecords []domains.DKIMRecord
+	for _, a := range a.DKIMRecords {
+		dkimRecords = append(dkimRecords, a.parse())
+	}
+	d.DKIMRecords = dkimRecords
+
+	var dmarcReportDomains []domains.DmarcReportDomain
+	for _, a := range a.DmarcReportDomains {
+		dmarcReportDomains = append(dmarcReportDomains, a.parse())
+	}
+	d.DmarcReportDomains = dmarcReportDomains
+
+	var sharedIPDomains []domains.SharedIPDomain
+	for _, a := range a.SharedIPDomains {
+		sharedIPDomains = append(sharedIPDomains, a.parse())
+	}
+	d.SharedIPDomains = sharedIPDomains
+
+	var sharedMXDomains []domains.SharedMXDomain
+	for _, a := range a.SharedMXDomains {
+		sharedMXDomains = append(sharedMXDomains, a.parse())
+	}
+	d.SharedMXDomains = sharedMXDomains
+
+	var sharedNSDomains []domains.SharedNSDomain
+	for _, a := range a.SharedNSDomains {
+		sharedNSDomains = append(sharedNSDomains, a.parse())
+	}
+	d.SharedNSDomains = sharedNSDomains
+
+	d.MailProvider = a.MailProvider.StringVal
+	d.DNSHost = a.DNSHost.StringVal
+
+	if a.Certificate != nil {
+		d.Certificate = a.Certificate.parse()
+	}
+
+	var certOrgDomains []domains.CertOrgDomain
+	for _, a := range a.CertOrgDomains {
+		certOrgDomains = append(certOrgDomains, a.parse())
+	}
+	d.CertOrgDomains = certOrgDomains
+
+	d.LastRanCT = a.LastRanCT.Timestamp
+	var ctLogDomains []domains.CTLogDomain
+	for _, a := range a.CTLogDomains {
+		ctLogDomains = append(ctLogDomains, a.parse())
+	}
+	d.CTLogDomains = ctLogDomains
+
+	var enrichmentErrors []domains.EnrichmentError
+	for _, a := range a.EnrichmentErrors {
+		enrichmentErrors = append(enrichmentErrors, a.parse())
+	}
+	d.EnrichmentErrors = enrichmentErrors
+
+	var strategyResults []*domains.StrategyResult
+	for _, a := range a.StrategyResults {
+		strategyResults = append(strategyResults, a.parse())
+	}
+	d.StrategyResults = strategyResults
+
+	var redirectChain []domains.RedirectHop
+	for _, a := range a.RedirectChain {
+		redirectChain = append(redirectChain, a.parse())
+	}
+	d.RedirectChain = redirectChain
+	d.WebLandingVariant = a.WebLandingVariant.StringVal
+
+	d.LastRanHomepageLinks = a.LastRanHomepageLinks.Timestamp
+	var homepageLinkDomains []domains.HomepageLinkDomain
+	for _, a := range a.HomepageLinkDomains {
+		homepageLinkDomains = append(homepageLinkDomains, a.parse())
+	}
+	d.HomepageLinkDomains = homepageLinkDomains
+
+	d.LastRanTrackers = a.LastRanTrackers.Timestamp
+	var trackerIDs []domains.TrackerID
+	for _, a := range a.TrackerIDs {
+		trackerIDs = append(trackerIDs, a.parse())
+	}
+	d.TrackerIDs = trackerIDs
+
+	var sharedTrackerDomains []domains.SharedTrackerDomain
+	for _, a := range a.SharedTrackerDomains {
+		sharedTrackerDomains = append(sharedTrackerDomains, a.parse())
+	}
+	d.SharedTrackerDomains = sharedTrackerDomains
+
+	if a.Organization != nil {
+		d.Organization = a.Organization.parse()
+	}
+	d.LastRanStructuredData = a.LastRanStructuredData.Timestamp
+	var structuredDataDomains []domains.StructuredDataDomain
+	for _, a := range a.StructuredDataDomains {
+		structuredDataDomains = append(structuredDataDomains, a.parse())
+	}
+	d.StructuredDataDomains = structuredDataDomains
+
 	return d
 }
 
@@ -181,6 +544,7 @@ type ARecordBQ struct {
 	CreatedAt time.Time `bigquery:"created_at"`
 	UpdatedAt time.Time `bigquery:"updated_at"`
 	IP        string    `bigquery:"ip"`
+	PTR       []string  `bigquery:"ptr"`
 }
 
 func newARecordBQ(record domains.ARecord) ARecordBQ {
@@ -188,6 +552,7 @@ func newARecordBQ(record domains.ARecord) ARecordBQ {
 		CreatedAt: record.CreatedAt,
 		UpdatedAt: record.UpdatedAt,
 		IP:        record.IP,
+		PTR:       record.PTR,
 	}
 }
 
@@ -196,6 +561,7 @@ func (a *ARecordBQ) parse() domains.ARecord {
 		CreatedAt: a.CreatedAt,
 		UpdatedAt: a.UpdatedAt,
 		IP:        a.IP,
+		PTR:       a.PTR,
 	}
 }
 
@@ -203,6 +569,7 @@ type AAAARecordBQ struct {
 	CreatedAt time.Time `bigquery:"created_at"`
 	UpdatedAt time.Time `bigquery:"updated_at"`
 	IPV6      string    `bigquery:"ip_v6"`
+	PTR       []string  `bigquery:"ptr"`
 }
 
 func newAAAARecordBQ(record domains.AAAARecord) AAAARecordBQ {
@@ -210,6 +577,7 @@ func newAAAARecordBQ(record domains.AAAARecord) AAAARecordBQ {
 		CreatedAt: record.CreatedAt,
 		UpdatedAt: record.UpdatedAt,
 		IPV6:      record.IPV6,
+		PTR:       record.PTR,
 	}
 }
 
@@ -218,6 +586,7 @@ func (a *AAAARecordBQ) parse() domains.AAAARecord {
 		CreatedAt: a.CreatedAt,
 		UpdatedAt: a.UpdatedAt,
 		IPV6:      a.IPV6,
+		PTR:       a.PTR,
 	}
 }
 
@@ -271,6 +640,137 @@ func (a *SOARecordBQ) parse() domains.SOARecord {
 	}
 }
 
+type NSRecordBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	NS        string    `bigquery:"ns"`
+}
+
+func newNSRecordBQ(record domains.NSRecord) NSRecordBQ {
+	return NSRecordBQ{
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		NS:        record.NS,
+	}
+}
+
+func (a *NSRecordBQ) parse() domains.NSRecord {
+	return domains.NSRecord{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		NS:        a.NS,
+	}
+}
+
+type TXTRecordBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	Text      string    `bigquery:"text"`
+}
+
+func newTXTRecordBQ(record domains.TXTRecord) TXTRecordBQ {
+	return TXTRecordBQ{
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Text:      record.Text,
+	}
+}
+
+func (a *TXTRecordBQ) parse() domains.TXTRecord {
+	return domains.TXTRecord{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Text:      a.Text,
+	}
+}
+
+type CNAMERecordBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	Host      string    `bigquery:"host"`
+	Target    string    `bigquery:"target"`
+}
+
+func newCNAMERecordBQ(record domains.CNAMERecord) CNAMERecordBQ {
+	return CNAMERecordBQ{
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Host:      record.Host,
+		Target:    record.Target,
+	}
+}
+
+func (a *CNAMERecordBQ) parse() domains.CNAMERecord {
+	return domains.CNAMERecord{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Host:      a.Host,
+		Target:    a.Target,
+	}
+}
+
+type CAARecordBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	Flag      int64     `bigquery:"flag"`
+	Tag       string    `bigquery:"tag"`
+	Value     string    `bigquery:"value"`
+}
+
+func newCAARecordBQ(record domains.CAARecord) CAARecordBQ {
+	return CAARecordBQ{
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Flag:      int64(record.Flag),
+		Tag:       record.Tag,
+		Value:     record.Value,
+	}
+}
+
+func (a *CAARecordBQ) parse() domains.CAARecord {
+	return domains.CAARecord{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Flag:      uint8(a.Flag),
+		Tag:       a.Tag,
+		Value:     a.Value,
+	}
+}
+
+type SRVRecordBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	Service   string    `bigquery:"service"`
+	Target    string    `bigquery:"target"`
+	Port      int64     `bigquery:"port"`
+	Priority  int64     `bigquery:"priority"`
+	Weight    int64     `bigquery:"weight"`
+}
+
+func newSRVRecordBQ(record domains.SRVRecord) SRVRecordBQ {
+	return SRVRecordBQ{
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Service:   record.Service,
+		Target:    record.Target,
+		Port:      int64(record.Port),
+		Priority:  int64(record.Priority),
+		Weight:    int64(record.Weight),
+	}
+}
+
+func (a *SRVRecordBQ) parse() domains.SRVRecord {
+	return domains.SRVRecord{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Service:   a.Service,
+		Target:    a.Target,
+		Port:      uint16(a.Port),
+		Priority:  uint16(a.Priority),
+		Weight:    uint16(a.Weight),
+	}
+}
+
 type SitemapBQ struct {
 	CreatedAt  time.Time           `bigquery:"created_at"`
 	UpdatedAt  time.Time           `bigquery:"updated_at"`
@@ -297,6 +797,7 @@ type MatchedDomainBQ struct {
 	CreatedAt  time.Time `bigquery:"created_at"`
 	UpdatedAt  time.Time `bigquery:"updated_at"`
 	DomainName string    `bigquery:"domain_name"`
+	Hosts      []string  `bigquery:"hosts"`
 }
 
 func newMatchedDomainBQ(record domains.MatchedDomain) MatchedDomainBQ {
@@ -304,6 +805,7 @@ func newMatchedDomainBQ(record domains.MatchedDomain) MatchedDomainBQ {
 		CreatedAt:  record.CreatedAt,
 		UpdatedAt:  record.UpdatedAt,
 		DomainName: record.DomainName,
+		Hosts:      record.Hosts,
 	}
 }
 
@@ -312,5 +814,705 @@ func (a *MatchedDomainBQ) parse() domains.MatchedDomain {
 		CreatedAt:  a.CreatedAt,
 		UpdatedAt:  a.UpdatedAt,
 		DomainName: a.DomainName,
+		Hosts:      a.Hosts,
+	}
+}
+
+type WhoisRecordBQ struct {
+	CreatedAt        time.Time              `bigquery:"created_at"`
+	UpdatedAt        time.Time              `bigquery:"updated_at"`
+	Source           bigquery.NullString    `bigquery:"source"`
+	Registrar        bigquery.NullString    `bigquery:"registrar"`
+	RegistrantOrg    bigquery.NullString    `bigquery:"registrant_org"`
+	RegistrantEmail  bigquery.NullString    `bigquery:"registrant_email"`
+	RegistrationDate bigquery.NullTimestamp `bigquery:"registration_date"`
+	ExpirationDate   bigquery.NullTimestamp `bigquery:"expiration_date"`
+	NameServers      []string               `bigquery:"name_servers"`
+}
+
+func newWhoisRecordBQ(record domains.WhoisRecord) WhoisRecordBQ {
+	return WhoisRecordBQ{
+		CreatedAt:        record.CreatedAt,
+		UpdatedAt:        record.UpdatedAt,
+		Source:           bigquery.NullString{StringVal: record.Source, Valid: record.Source != ""},
+		Registrar:        bigquery.NullString{StringVal: record.Registrar, Valid: record.Registrar != ""},
+		RegistrantOrg:    bigquery.NullString{StringVal: record.RegistrantOrg, Valid: record.RegistrantOrg != ""},
+		RegistrantEmail:  bigquery.NullString{StringVal: record.RegistrantEmail, Valid: record.RegistrantEmail != ""},
+		RegistrationDate: bigquery.NullTimestamp{Timestamp: record.RegistrationDate, Valid: !record.RegistrationDate.IsZero()},
+		ExpirationDate:   bigquery.NullTimestamp{Timestamp: record.ExpirationDate, Valid: !record.ExpirationDate.IsZero()},
+		NameServers:      record.NameServers,
+	}
+}
+
+func (a *WhoisRecordBQ) parse() *domains.WhoisRecord {
+	return &domains.WhoisRecord{
+		CreatedAt:        a.CreatedAt,
+		UpdatedAt:        a.UpdatedAt,
+		Source:           a.Source.StringVal,
+		Registrar:        a.Registrar.StringVal,
+		RegistrantOrg:    a.RegistrantOrg.StringVal,
+		RegistrantEmail:  a.RegistrantEmail.StringVal,
+		RegistrationDate: a.RegistrationDate.Timestamp,
+		ExpirationDate:   a.ExpirationDate.Timestamp,
+		NameServers:      a.NameServers,
+	}
+}
+
+type SpfIncludeDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	Include    string    `bigquery:"include"`
+	Generic    bool      `bigquery:"generic"`
+	Hosts      []string  `bigquery:"hosts"`
+}
+
+func newSpfIncludeDomainBQ(record domains.SpfIncludeDomain) SpfIncludeDomainBQ {
+	return SpfIncludeDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		Include:    record.Include,
+		Generic:    record.Generic,
+		Hosts:      record.Hosts,
+	}
+}
+
+func (a *SpfIncludeDomainBQ) parse() domains.SpfIncludeDomain {
+	return domains.SpfIncludeDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		Include: a.Include,
+		Generic: a.Generic,
+	}
+}
+
+type HomepageLinkDomainBQ struct {
+	CreatedAt   time.Time `bigquery:"created_at"`
+	UpdatedAt   time.Time `bigquery:"updated_at"`
+	DomainName  string    `bigquery:"domain_name"`
+	Hosts       []string  `bigquery:"hosts"`
+	Sections    []string  `bigquery:"sections"`
+	AnchorTexts []string  `bigquery:"anchor_texts"`
+	Generic     bool      `bigquery:"generic"`
+}
+
+func newHomepageLinkDomainBQ(record domains.HomepageLinkDomain) HomepageLinkDomainBQ {
+	return HomepageLinkDomainBQ{
+		CreatedAt:   record.CreatedAt,
+		UpdatedAt:   record.UpdatedAt,
+		DomainName:  record.DomainName,
+		Hosts:       record.Hosts,
+		Sections:    record.Sections,
+		AnchorTexts: record.AnchorTexts,
+		Generic:     record.Generic,
+	}
+}
+
+func (a *HomepageLinkDomainBQ) parse() domains.HomepageLinkDomain {
+	return domains.HomepageLinkDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		Sections:    a.Sections,
+		AnchorTexts: a.AnchorTexts,
+		Generic:     a.Generic,
+	}
+}
+
+type DmarcRecordBQ struct {
+	CreatedAt       time.Time           `bigquery:"created_at"`
+	UpdatedAt       time.Time           `bigquery:"updated_at"`
+	Raw             bigquery.NullString `bigquery:"raw"`
+	Policy          bigquery.NullString `bigquery:"p"`
+	SubdomainPolicy bigquery.NullString `bigquery:"sp"`
+	Pct             bigquery.NullInt64  `bigquery:"pct"`
+	Rua             []string            `bigquery:"rua"`
+	Ruf             []string            `bigquery:"ruf"`
+}
+
+func newDmarcRecordBQ(record domains.DmarcRecord) DmarcRecordBQ {
+	return DmarcRecordBQ{
+		CreatedAt:       record.CreatedAt,
+		UpdatedAt:       record.UpdatedAt,
+		Raw:             bigquery.NullString{StringVal: record.Raw, Valid: record.Raw != ""},
+		Policy:          bigquery.NullString{StringVal: record.Policy, Valid: record.Policy != ""},
+		SubdomainPolicy: bigquery.NullString{StringVal: record.SubdomainPolicy, Valid: record.SubdomainPolicy != ""},
+		Pct:             bigquery.NullInt64{Int64: int64(record.Pct), Valid: true},
+		Rua:             record.Rua,
+		Ruf:             record.Ruf,
+	}
+}
+
+func (a *DmarcRecordBQ) parse() *domains.DmarcRecord {
+	return &domains.DmarcRecord{
+		CreatedAt:       a.CreatedAt,
+		UpdatedAt:       a.UpdatedAt,
+		Raw:             a.Raw.StringVal,
+		Policy:          a.Policy.StringVal,
+		SubdomainPolicy: a.SubdomainPolicy.StringVal,
+		Pct:             int(a.Pct.Int64),
+		Rua:             a.Rua,
+		Ruf:             a.Ruf,
+	}
+}
+
+type DKIMRecordBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	Selector  string    `bigquery:"selector"`
+	KeyType   string    `bigquery:"key_type"`
+	PublicKey string    `bigquery:"public_key"`
+}
+
+func newDKIMRecordBQ(record domains.DKIMRecord) DKIMRecordBQ {
+	return DKIMRecordBQ{
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Selector:  record.Selector,
+		KeyType:   record.KeyType,
+		PublicKey: record.PublicKey,
+	}
+}
+
+func (a *DKIMRecordBQ) parse() domains.DKIMRecord {
+	return domains.DKIMRecord{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Selector:  a.Selector,
+		KeyType:   a.KeyType,
+		PublicKey: a.PublicKey,
+	}
+}
+
+type DmarcReportDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	Mailbox    string    `bigquery:"mailbox"`
+	Hosts      []string  `bigquery:"hosts"`
+}
+
+func newDmarcReportDomainBQ(record domains.DmarcReportDomain) DmarcReportDomainBQ {
+	return DmarcReportDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		Mailbox:    record.Mailbox,
+		Hosts:      record.Hosts,
+	}
+}
+
+func (a *DmarcReportDomainBQ) parse() domains.DmarcReportDomain {
+	return domains.DmarcReportDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		Mailbox: a.Mailbox,
+	}
+}
+
+type SharedIPDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	IP         string    `bigquery:"ip"`
+}
+
+func newSharedIPDomainBQ(record domains.SharedIPDomain) SharedIPDomainBQ {
+	return SharedIPDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		IP:         record.IP,
+	}
+}
+
+func (a *SharedIPDomainBQ) parse() domains.SharedIPDomain {
+	return domains.SharedIPDomain{
+		MatchedDomain: domains.MatchedDomain{CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName},
+		IP:            a.IP,
+	}
+}
+
+type SharedMXDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	MX         string    `bigquery:"mx"`
+}
+
+func newSharedMXDomainBQ(record domains.SharedMXDomain) SharedMXDomainBQ {
+	return SharedMXDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		MX:         record.MX,
+	}
+}
+
+func (a *SharedMXDomainBQ) parse() domains.SharedMXDomain {
+	return domains.SharedMXDomain{
+		MatchedDomain: domains.MatchedDomain{CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName},
+		MX:            a.MX,
+	}
+}
+
+type TrackerIDBQ struct {
+	Type string `bigquery:"type"`
+	ID   string `bigquery:"id"`
+	// Account is stored to find the domains sharing an account, see domains.TrackerID.Account
+	Account string `bigquery:"account"`
+	Source  string `bigquery:"source"`
+}
+
+func newTrackerIDBQ(record domains.TrackerID) TrackerIDBQ {
+	return TrackerIDBQ{Type: record.Type, ID: record.ID, Account: record.Account(), Source: record.Source}
+}
+
+func (a *TrackerIDBQ) parse() domains.TrackerID {
+	return domains.TrackerID{Type: a.Type, ID: a.ID, Source: a.Source}
+}
+
+type SharedTrackerDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	Tracker    string    `bigquery:"tracker"`
+	Account    string    `bigquery:"account"`
+}
+
+func newSharedTrackerDomainBQ(record domains.SharedTrackerDomain) SharedTrackerDomainBQ {
+	return SharedTrackerDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		Tracker:    record.Tracker,
+		Account:    record.Account,
+	}
+}
+
+func (a *SharedTrackerDomainBQ) parse() domains.SharedTrackerDomain {
+	return domains.SharedTrackerDomain{
+		MatchedDomain: domains.MatchedDomain{CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName},
+		Tracker:       a.Tracker,
+		Account:       a.Account,
+	}
+}
+
+type OrganizationBQ struct {
+	CreatedAt time.Time `bigquery:"created_at"`
+	UpdatedAt time.Time `bigquery:"updated_at"`
+	// DisplayName is stored for analysts, see domains.Organization.DisplayName
+	DisplayName         bigquery.NullString `bigquery:"display_name"`
+	Name                bigquery.NullString `bigquery:"name"`
+	LegalName           bigquery.NullString `bigquery:"legal_name"`
+	URL                 bigquery.NullString `bigquery:"url"`
+	SameAs              []string            `bigquery:"same_as"`
+	ParentOrganizations []OrganizationRefBQ `bigquery:"parent_organizations"`
+	SubOrganizations    []OrganizationRefBQ `bigquery:"sub_organizations"`
+	SiteName            bigquery.NullString `bigquery:"site_name"`
+	Title               bigquery.NullString `bigquery:"title"`
+}
+
+func newOrganizationBQ(record domains.Organization) OrganizationBQ {
+	o := OrganizationBQ{
+		CreatedAt:   record.CreatedAt,
+		UpdatedAt:   record.UpdatedAt,
+		DisplayName: bigquery.NullString{StringVal: record.DisplayName(), Valid: record.DisplayName() != ""},
+		Name:        bigquery.NullString{StringVal: record.Name, Valid: record.Name != ""},
+		LegalName:   bigquery.NullString{StringVal: record.LegalName, Valid: record.LegalName != ""},
+		URL:         bigquery.NullString{StringVal: record.URL, Valid: record.URL != ""},
+		SameAs:      record.SameAs,
+		SiteName:    bigquery.NullString{StringVal: record.SiteName, Valid: record.SiteName != ""},
+		Title:       bigquery.NullString{StringVal: record.Title, Valid: record.Title != ""},
+	}
+	for _, r := range record.ParentOrganizations {
+		o.ParentOrganizations = append(o.ParentOrganizations, newOrganizationRefBQ(r))
+	}
+	for _, r := range record.SubOrganizations {
+		o.SubOrganizations = append(o.SubOrganizations, newOrganizationRefBQ(r))
+	}
+	return o
+}
+
+func (a *OrganizationBQ) parse() *domains.Organization {
+	o := &domains.Organization{
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Name:      a.Name.StringVal,
+		LegalName: a.LegalName.StringVal,
+		URL:       a.URL.StringVal,
+		SameAs:    a.SameAs,
+		SiteName:  a.SiteName.StringVal,
+		Title:     a.Title.StringVal,
+	}
+	for _, r := range a.ParentOrganizations {
+		o.ParentOrganizations = append(o.ParentOrganizations, r.parse())
+	}
+	for _, r := range a.SubOrganizations {
+		o.SubOrganizations = append(o.SubOrganizations, r.parse())
+	}
+	return o
+}
+
+type OrganizationRefBQ struct {
+	Name bigquery.NullString `bigquery:"name"`
+	URL  bigquery.NullString `bigquery:"url"`
+}
+
+func newOrganizationRefBQ(record domains.OrganizationRef) OrganizationRefBQ {
+	return OrganizationRefBQ{
+		Name: bigquery.NullString{StringVal: record.Name, Valid: record.Name != ""},
+		URL:  bigquery.NullString{StringVal: record.URL, Valid: record.URL != ""},
+	}
+}
+
+func (a *OrganizationRefBQ) parse() domains.OrganizationRef {
+	return domains.OrganizationRef{Name: a.Name.StringVal, URL: a.URL.StringVal}
+}
+
+type StructuredDataDomainBQ struct {
+	CreatedAt     time.Time `bigquery:"created_at"`
+	UpdatedAt     time.Time `bigquery:"updated_at"`
+	DomainName    string    `bigquery:"domain_name"`
+	Hosts         []string  `bigquery:"hosts"`
+	Relationships []string  `bigquery:"relationships"`
+	Generic       bool      `bigquery:"generic"`
+}
+
+func newStructuredDataDomainBQ(record domains.StructuredDataDomain) StructuredDataDomainBQ {
+	return StructuredDataDomainBQ{
+		CreatedAt:     record.CreatedAt,
+		UpdatedAt:     record.UpdatedAt,
+		DomainName:    record.DomainName,
+		Hosts:         record.Hosts,
+		Relationships: record.Relationships,
+		Generic:       record.Generic,
+	}
+}
+
+func (a *StructuredDataDomainBQ) parse() domains.StructuredDataDomain {
+	return domains.StructuredDataDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		Relationships: a.Relationships,
+		Generic:       a.Generic,
+	}
+}
+
+type SharedNSDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	NS         string    `bigquery:"ns"`
+}
+
+func newSharedNSDomainBQ(record domains.SharedNSDomain) SharedNSDomainBQ {
+	return SharedNSDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		NS:         record.NS,
+	}
+}
+
+func (a *SharedNSDomainBQ) parse() domains.SharedNSDomain {
+	return domains.SharedNSDomain{
+		MatchedDomain: domains.MatchedDomain{CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName},
+		NS:            a.NS,
+	}
+}
+
+type CertificateBQ struct {
+	CreatedAt           time.Time            `bigquery:"created_at"`
+	UpdatedAt           time.Time            `bigquery:"updated_at"`
+	Endpoint            bigquery.NullString  `bigquery:"endpoint"`
+	Issuer              bigquery.NullString  `bigquery:"issuer"`
+	IssuerOrganization  bigquery.NullString  `bigquery:"issuer_organization"`
+	SubjectCommonName   bigquery.NullString  `bigquery:"subject_common_name"`
+	SubjectOrganization bigquery.NullString  `bigquery:"subject_organization"`
+	Serial              bigquery.NullString  `bigquery:"serial"`
+	FingerprintSHA256   bigquery.NullString  `bigquery:"fingerprint_sha256"`
+	NotBefore           time.Time            `bigquery:"not_before"`
+	NotAfter            time.Time            `bigquery:"not_after"`
+	KeyType             bigquery.NullString  `bigquery:"key_type"`
+	SelfSigned          bool                 `bigquery:"self_signed"`
+	ChainValid          bool                 `bigquery:"chain_valid"`
+	VerifyError         bigquery.NullString  `bigquery:"verify_error"`
+	Chain               []CertificateChainBQ `bigquery:"chain"`
+}
+
+func newCertificateBQ(record domains.Certificate) CertificateBQ {
+	c := CertificateBQ{
+		CreatedAt:           record.CreatedAt,
+		UpdatedAt:           record.UpdatedAt,
+		Endpoint:            bigquery.NullString{StringVal: record.Endpoint, Valid: record.Endpoint != ""},
+		Issuer:              bigquery.NullString{StringVal: record.Issuer, Valid: record.Issuer != ""},
+		IssuerOrganization:  bigquery.NullString{StringVal: record.IssuerOrganization, Valid: record.IssuerOrganization != ""},
+		SubjectCommonName:   bigquery.NullString{StringVal: record.SubjectCommonName, Valid: record.SubjectCommonName != ""},
+		SubjectOrganization: bigquery.NullString{StringVal: record.SubjectOrganization, Valid: record.SubjectOrganization != ""},
+		Serial:              bigquery.NullString{StringVal: record.Serial, Valid: record.Serial != ""},
+		FingerprintSHA256:   bigquery.NullString{StringVal: record.FingerprintSHA256, Valid: record.FingerprintSHA256 != ""},
+		NotBefore:           record.NotBefore,
+		NotAfter:            record.NotAfter,
+		KeyType:             bigquery.NullString{StringVal: record.KeyType, Valid: record.KeyType != ""},
+		SelfSigned:          record.SelfSigned,
+		ChainValid:          record.ChainValid,
+		VerifyError:         bigquery.NullString{StringVal: record.VerifyError, Valid: record.VerifyError != ""},
+	}
+	for _, a := range record.Chain {
+		c.Chain = append(c.Chain, newCertificateChainBQ(a))
+	}
+	return c
+}
+
+func (a *CertificateBQ) parse() *domains.Certificate {
+	c := &domains.Certificate{
+		CreatedAt:           a.CreatedAt,
+		UpdatedAt:           a.UpdatedAt,
+		Endpoint:            a.Endpoint.StringVal,
+		Issuer:              a.Issuer.StringVal,
+		IssuerOrganization:  a.IssuerOrganization.StringVal,
+		SubjectCommonName:   a.SubjectCommonName.StringVal,
+		SubjectOrganization: a.SubjectOrganization.StringVal,
+		Serial:              a.Serial.StringVal,
+		FingerprintSHA256:   a.FingerprintSHA256.StringVal,
+		NotBefore:           a.NotBefore,
+		NotAfter:            a.NotAfter,
+		KeyType:             a.KeyType.StringVal,
+		SelfSigned:          a.SelfSigned,
+		ChainValid:          a.ChainValid,
+		VerifyError:         a.VerifyError.StringVal,
+	}
+	for _, ch := range a.Chain {
+		c.Chain = append(c.Chain, ch.parse())
+	}
+	return c
+}
+
+type CertificateChainBQ struct {
+	Subject           bigquery.NullString `bigquery:"subject"`
+	Issuer            bigquery.NullString `bigquery:"issuer"`
+	FingerprintSHA256 bigquery.NullString `bigquery:"fingerprint_sha256"`
+	NotBefore         time.Time           `bigquery:"not_before"`
+	NotAfter          time.Time           `bigquery:"not_after"`
+}
+
+func newCertificateChainBQ(record domains.CertificateChain) CertificateChainBQ {
+	return CertificateChainBQ{
+		Subject:           bigquery.NullString{StringVal: record.Subject, Valid: record.Subject != ""},
+		Issuer:            bigquery.NullString{StringVal: record.Issuer, Valid: record.Issuer != ""},
+		FingerprintSHA256: bigquery.NullString{StringVal: record.FingerprintSHA256, Valid: record.FingerprintSHA256 != ""},
+		NotBefore:         record.NotBefore,
+		NotAfter:          record.NotAfter,
+	}
+}
+
+func (a *CertificateChainBQ) parse() domains.CertificateChain {
+	return domains.CertificateChain{
+		Subject:           a.Subject.StringVal,
+		Issuer:            a.Issuer.StringVal,
+		FingerprintSHA256: a.FingerprintSHA256.StringVal,
+		NotBefore:         a.NotBefore,
+		NotAfter:          a.NotAfter,
+	}
+}
+
+type CertOrgDomainBQ struct {
+	CreatedAt    time.Time `bigquery:"created_at"`
+	UpdatedAt    time.Time `bigquery:"updated_at"`
+	DomainName   string    `bigquery:"domain_name"`
+	Organization string    `bigquery:"organization"`
+}
+
+func newCertOrgDomainBQ(record domains.CertOrgDomain) CertOrgDomainBQ {
+	return CertOrgDomainBQ{
+		CreatedAt:    record.CreatedAt,
+		UpdatedAt:    record.UpdatedAt,
+		DomainName:   record.DomainName,
+		Organization: record.Organization,
+	}
+}
+
+func (a *CertOrgDomainBQ) parse() domains.CertOrgDomain {
+	return domains.CertOrgDomain{
+		MatchedDomain: domains.MatchedDomain{CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName},
+		Organization:  a.Organization,
+	}
+}
+
+type CertSansDomainBQ struct {
+	CreatedAt  time.Time `bigquery:"created_at"`
+	UpdatedAt  time.Time `bigquery:"updated_at"`
+	DomainName string    `bigquery:"domain_name"`
+	Endpoints  []string  `bigquery:"endpoints"`
+	Hosts      []string  `bigquery:"hosts"`
+}
+
+func newCertSansDomainBQ(record domains.CertSansDomain) CertSansDomainBQ {
+	return CertSansDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		Endpoints:  record.Endpoints,
+		Hosts:      record.Hosts,
+	}
+}
+
+func (a *CertSansDomainBQ) parse() domains.CertSansDomain {
+	return domains.CertSansDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		Endpoints: a.Endpoints,
+	}
+}
+
+type CTLogDomainBQ struct {
+	CreatedAt  time.Time              `bigquery:"created_at"`
+	UpdatedAt  time.Time              `bigquery:"updated_at"`
+	DomainName string                 `bigquery:"domain_name"`
+	FirstSeen  bigquery.NullTimestamp `bigquery:"first_seen"`
+	LastSeen   bigquery.NullTimestamp `bigquery:"last_seen"`
+	Hosts      []string               `bigquery:"hosts"`
+}
+
+func newCTLogDomainBQ(record domains.CTLogDomain) CTLogDomainBQ {
+	return CTLogDomainBQ{
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		DomainName: record.DomainName,
+		FirstSeen:  bigquery.NullTimestamp{Timestamp: record.FirstSeen, Valid: !record.FirstSeen.IsZero()},
+		LastSeen:   bigquery.NullTimestamp{Timestamp: record.LastSeen, Valid: !record.LastSeen.IsZero()},
+		Hosts:      record.Hosts,
+	}
+}
+
+func (a *CTLogDomainBQ) parse() domains.CTLogDomain {
+	return domains.CTLogDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		FirstSeen: a.FirstSeen.Timestamp,
+		LastSeen:  a.LastSeen.Timestamp,
+	}
+}
+
+type EnrichmentErrorBQ struct {
+	Strategy   string    `bigquery:"strategy"`
+	Class      string    `bigquery:"class"`
+	Message    string    `bigquery:"message"`
+	OccurredAt time.Time `bigquery:"occurred_at"`
+}
+
+func newEnrichmentErrorBQ(record domains.EnrichmentError) EnrichmentErrorBQ {
+	return EnrichmentErrorBQ{
+		Strategy:   record.Strategy,
+		Class:      record.Class,
+		Message:    record.Message,
+		OccurredAt: record.OccurredAt,
+	}
+}
+
+func (a *EnrichmentErrorBQ) parse() domains.EnrichmentError {
+	return domains.EnrichmentError{
+		Strategy:   a.Strategy,
+		Class:      a.Class,
+		Message:    a.Message,
+		OccurredAt: a.OccurredAt,
+	}
+}
+
+type StrategyResultBQ struct {
+	Strategy string                 `bigquery:"strategy"`
+	LastRan  bigquery.NullTimestamp `bigquery:"last_ran"`
+	Domains  []StrategyDomainBQ     `bigquery:"domains"`
+}
+
+func newStrategyResultBQ(record domains.StrategyResult) StrategyResultBQ {
+	var doms []StrategyDomainBQ
+	for _, a := range record.Domains {
+		doms = append(doms, newStrategyDomainBQ(a))
+	}
+	return StrategyResultBQ{
+		Strategy: record.Strategy,
+		LastRan:  bigquery.NullTimestamp{Timestamp: record.LastRan, Valid: !record.LastRan.IsZero()},
+		Domains:  doms,
+	}
+}
+
+func (a *StrategyResultBQ) parse() *domains.StrategyResult {
+	var doms []domains.StrategyDomain
+	for _, a := range a.Domains {
+		doms = append(doms, a.parse())
+	}
+	return &domains.StrategyResult{
+		Strategy: a.Strategy,
+		LastRan:  a.LastRan.Timestamp,
+		Domains:  doms,
+	}
+}
+
+type StrategyDomainBQ struct {
+	CreatedAt    time.Time `bigquery:"created_at"`
+	UpdatedAt    time.Time `bigquery:"updated_at"`
+	DomainName   string    `bigquery:"domain_name"`
+	Relationship string    `bigquery:"relationship"`
+	Hosts        []string  `bigquery:"hosts"`
+}
+
+func newStrategyDomainBQ(record domains.StrategyDomain) StrategyDomainBQ {
+	return StrategyDomainBQ{
+		CreatedAt:    record.CreatedAt,
+		UpdatedAt:    record.UpdatedAt,
+		DomainName:   record.DomainName,
+		Relationship: record.Relationship,
+		Hosts:        record.Hosts,
+	}
+}
+
+func (a *StrategyDomainBQ) parse() domains.StrategyDomain {
+	return domains.StrategyDomain{
+		MatchedDomain: domains.MatchedDomain{
+			CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DomainName: a.DomainName, Hosts: a.Hosts,
+		},
+		Relationship: a.Relationship,
+	}
+}
+
+type RedirectHopBQ struct {
+	Start      string              `bigquery:"start"`
+	URL        string              `bigquery:"url"`
+	Method     string              `bigquery:"method"`
+	StatusCode int64               `bigquery:"status_code"`
+	Type       bigquery.NullString `bigquery:"type"`
+	Location   bigquery.NullString `bigquery:"location"`
+	DurationMs int64               `bigquery:"duration_ms"`
+}
+
+func newRedirectHopBQ(record domains.RedirectHop) RedirectHopBQ {
+	return RedirectHopBQ{
+		Start:      record.Start,
+		URL:        record.URL,
+		Method:     record.Method,
+		StatusCode: int64(record.StatusCode),
+		Type:       bigquery.NullString{StringVal: record.Type, Valid: record.Type != ""},
+		Location:   bigquery.NullString{StringVal: record.Location, Valid: record.Location != ""},
+		DurationMs: record.Duration.Milliseconds(),
+	}
+}
+
+func (a *RedirectHopBQ) parse() domains.RedirectHop {
+	return domains.RedirectHop{
+		Start:      a.Start,
+		URL:        a.URL,
+		Method:     a.Method,
+		StatusCode: int(a.StatusCode),
+		Type:       a.Type.StringVal,
+		Location:   a.Location.StringVal,
+		Duration:   time.Duration(a.DurationMs) * time.Millisecond,
 	}
 }
@@ -0,0 +1,53 @@
+package bq
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/herzs11/domwalk/domains"
+)
+
+func TestDomainBQRoundTrip(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	d, err := domains.NewDomain("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SuccessfulWebLanding = true
+	d.WebRedirectURLFinal = "https://www.example.net/home"
+	d.WebLandingVariant = "https://www.example.com"
+	d.LastRanWebRedirect = now
+	d.WebRedirectDomains = []domains.WebRedirectDomain{
+		{MatchedDomain: domains.MatchedDomain{CreatedAt: now, UpdatedAt: now, DomainName: "example.net"}},
+	}
+	d.RedirectChain = []domains.RedirectHop{
+		{Start: "https://www.example.com", URL: "https://www.example.com", Method: "GET", StatusCode: 301},
+	}
+
+	got := newDomainBQ(d)
+	if !got.WebRedirectURLFinal.Valid {
+		t.Error("WebRedirectURLFinal not set on DomainBQ")
+	}
+	p := got.parse()
+	if p.WebRedirectURLFinal != d.WebRedirectURLFinal {
+		t.Errorf("WebRedirectURLFinal = %q, want %q", p.WebRedirectURLFinal, d.WebRedirectURLFinal)
+	}
+	if p.WebLandingVariant != d.WebLandingVariant {
+		t.Errorf("WebLandingVariant = %q, want %q", p.WebLandingVariant, d.WebLandingVariant)
+	}
+	if !p.SuccessfulWebLanding || !p.LastRanWebRedirect.Equal(now) {
+		t.Errorf("web landing not preserved: %v %v", p.SuccessfulWebLanding, p.LastRanWebRedirect)
+	}
+	if !reflect.DeepEqual(p.WebRedirectDomains, d.WebRedirectDomains) {
+		t.Errorf("WebRedirectDomains = %v, want %v", p.WebRedirectDomains, d.WebRedirectDomains)
+	}
+	if !reflect.DeepEqual(p.RedirectChain, d.RedirectChain) {
+		t.Errorf("RedirectChain = %v, want %v", p.RedirectChain, d.RedirectChain)
+	}
+
+	d.WebRedirectURLFinal = ""
+	if newDomainBQ(d).WebRedirectURLFinal.Valid {
+		t.Error("empty WebRedirectURLFinal stored as valid")
+	}
+}